@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jon-ski/dhcpset/pkg/dhcp/pkt"
+	"github.com/jon-ski/dhcpset/pkg/pcap"
+)
+
+// ErrInvalidPackets is returned by runDecode with -validate when a packet
+// breaks a protocol rule
+var ErrInvalidPackets = errors.New("invalid packets")
+
+// runDecode implements `dhcpset decode [-json] [-validate] [file]`. It reads
+// a raw DHCP packet (UDP payload) or a pcap/pcapng capture from file, or
+// stdin, and prints the packets.
+func runDecode(args []string) error {
+	fs := flag.NewFlagSet("decode", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the packet as JSON")
+	validate := fs.Bool("validate", false, "print the protocol rules each packet breaks")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if fs.NArg() > 0 && fs.Arg(0) != "-" {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return fmt.Errorf("failed to open packet: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read packet: %w", err)
+	}
+
+	var pkts []*pkt.Pkt
+	captured, err := pcap.ReadDHCP(bytes.NewReader(b))
+	switch {
+	case errors.Is(err, pcap.ErrBadMagic):
+		p, err := pkt.NewFromBytes(b)
+		if err != nil {
+			return fmt.Errorf("failed to decode packet: %w", err)
+		}
+		pkts = append(pkts, p)
+	case err != nil:
+		return fmt.Errorf("failed to read capture: %w", err)
+	default:
+		for _, c := range captured {
+			pkts = append(pkts, c.Pkt)
+		}
+	}
+
+	if *validate {
+		return validatePkts(pkts)
+	}
+	if *asJSON {
+		var v any = pkts
+		if len(pkts) == 1 {
+			v = pkts[0]
+		}
+		out, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode JSON: %w", err)
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+	for i, p := range pkts {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Print(p.Format())
+	}
+	return nil
+}
+
+// validatePkts prints every rule broken by each packet
+func validatePkts(pkts []*pkt.Pkt) error {
+	invalid := false
+	for i, p := range pkts {
+		err := p.Validate()
+		var verrs pkt.ValidationErrors
+		if !errors.As(err, &verrs) {
+			fmt.Printf("packet %d (%s, xid 0x%08x): ok\n", i+1, pktType(p), p.Header.XID)
+			continue
+		}
+		invalid = true
+		fmt.Printf("packet %d (%s, xid 0x%08x):\n", i+1, pktType(p), p.Header.XID)
+		for _, verr := range verrs {
+			fmt.Printf("    %v\n", verr)
+		}
+	}
+	if invalid {
+		return ErrInvalidPackets
+	}
+	return nil
+}
+
+// pktType names the DHCP message type of p, or BOOTP
+func pktType(p *pkt.Pkt) string {
+	if p.IsBOOTP() {
+		return "BOOTP"
+	}
+	return p.MessageType().String()
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/lipgloss/list"
 	"github.com/jon-ski/dhcpset/internal/styles"
+	"github.com/jon-ski/dhcpset/pkg/dhcp/pkt"
 )
 
 type listenModel struct {
@@ -78,12 +79,31 @@ func (m listenModel) ViewItem(i int) string {
 	if i < 0 || i >= len(m.list) {
 		return ""
 	}
-	return fmt.Sprintf(
+	hwaddr := m.list[i].hwaddr.String()
+	if hwaddr == "" {
+		// InfiniBand clients only identify themselves by client ID
+		hwaddr = pkt.HardwareTypeName(m.list[i].htype)
+	}
+	s := fmt.Sprintf(
 		"%17s | %08x | %s",
-		m.list[i].hwaddr.String(),
+		hwaddr,
 		m.list[i].xid,
 		m.list[i].tstamp.Local().Format("15:04:05"),
 	)
+	if m.list[i].bootp {
+		s += " | BOOTP"
+	}
+	if id := m.list[i].id.String(); id != hwaddr {
+		s += fmt.Sprintf(" | id %s", id)
+	}
+	if m.list[i].circuitID != "" || m.list[i].remoteID != "" {
+		s += fmt.Sprintf(
+			" | circuit %s remote %s",
+			m.list[i].circuitID,
+			m.list[i].remoteID,
+		)
+	}
+	return s
 }
 
 var listenEnumeratorStyle = lipgloss.NewStyle().
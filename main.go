@@ -1,10 +1,14 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"flag"
 	"fmt"
 	"log/slog"
 	"net"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/key"
@@ -12,6 +16,8 @@ import (
 	"github.com/charmbracelet/huh"
 	"github.com/charmbracelet/log"
 	"github.com/jon-ski/dhcpset/pkg/dhcp"
+	"github.com/jon-ski/dhcpset/pkg/dhcp/pkt"
+	"github.com/jon-ski/dhcpset/pkg/pcap"
 )
 
 func chooseInterface() (net.Interface, error) {
@@ -52,6 +58,8 @@ func chooseInterface() (net.Interface, error) {
 type config struct {
 	iface net.Interface
 	addr  net.IP
+	// timeout limits each assignment, zero means no limit
+	timeout time.Duration
 }
 
 func chooseIP(iface net.Interface) (net.IP, error) {
@@ -106,6 +114,18 @@ func chooseConfig() (c config, err error) {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "decode" {
+		err := runDecode(os.Args[2:])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	pcapPath := flag.String("pcap", "", "write all DHCP traffic to a pcap file (pcapng if it ends in .pcapng)")
+	timeout := flag.Duration("timeout", 0, "give up on an assignment after this long, 0 for no limit")
+	flag.Parse()
 
 	f, err := tea.LogToFile("debug.log", "dhcpset")
 	if err != nil {
@@ -128,6 +148,7 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	cfg.timeout = *timeout
 	log.Infof("using interface %v with IP %v", cfg.iface.Name, cfg.addr)
 
 	// Create a listener
@@ -136,7 +157,25 @@ func main() {
 	if err != nil {
 		log.Fatalf("failed to create server: %v", err)
 	}
-	defer s.Close()
+
+	// Capture traffic
+	if *pcapPath != "" {
+		log.Debug("capturing traffic", "file", *pcapPath)
+		pf, err := os.Create(*pcapPath)
+		if err != nil {
+			log.Fatalf("failed to create capture file: %v", err)
+		}
+		defer pf.Close()
+		newWriter := pcap.NewWriter
+		if strings.HasSuffix(*pcapPath, ".pcapng") {
+			newWriter = pcap.NewNgWriter
+		}
+		w, err := newWriter(pf)
+		if err != nil {
+			log.Fatalf("failed to start capture: %v", err)
+		}
+		s.SetCapture(w)
+	}
 
 	// Listen for packets
 	log.Debug("setting up listener")
@@ -144,12 +183,18 @@ func main() {
 	if err != nil {
 		log.Fatalf("failed to listen: %v", err)
 	}
+	// deferred after the capture file, so the server stops writing to it
+	// before it is closed
+	defer s.Close()
 
 	// model
 	m := newModel(cfg, s)
 
 	log.Debug("listening for discover packets")
-	m.discoverChan = sniffMacs(s, m.stopChan)
+	ctx, stopSniff := context.WithCancel(context.Background())
+	defer stopSniff()
+	m.stopSniff = stopSniff
+	m.discoverChan = sniffMacs(ctx, s)
 
 	// Run the UI
 	p := tea.NewProgram(m, tea.WithAltScreen())
@@ -160,43 +205,60 @@ func main() {
 }
 
 type discoverInfo struct {
+	id     dhcp.ClientID
+	htype  uint8
 	hwaddr net.HardwareAddr
 	xid    uint32
 	tstamp time.Time
+
+	// relay agent information inserted by a switch, if any
+	circuitID string
+	remoteID  string
+
+	// bootp is set for plain BOOTP clients
+	bootp bool
 }
 
-func newDiscoverInfo(hwaddr net.HardwareAddr, xid uint32) discoverInfo {
-	return discoverInfo{
-		hwaddr: hwaddr,
-		xid:    xid,
+func newDiscoverInfo(p *pkt.Pkt) discoverInfo {
+	info := discoverInfo{
+		id:     dhcp.ClientIDFromPkt(p),
+		htype:  p.Header.HType,
+		hwaddr: dhcp.ClientHwAddr(p),
+		xid:    p.Header.XID,
 		tstamp: time.Now(),
+		bootp:  p.IsBOOTP(),
+	}
+	if relay, ok := p.RelayAgentInfo(); ok {
+		info.circuitID = relay.CircuitIDString()
+		info.remoteID = relay.RemoteIDString()
 	}
+	return info
 }
 
-func sniffMacs(s *dhcp.Server, stop chan struct{}) chan discoverInfo {
+func sniffMacs(ctx context.Context, s *dhcp.Server) chan discoverInfo {
 	info := make(chan discoverInfo)
+	// one subscription for the whole run, so no DISCOVER is missed
+	// between two reads
+	sub := s.Subscribe(dhcp.DiscoverFilter())
 	go func() {
+		defer sub.Close()
 		for {
-			// if running, continue. If stopped, break
-			select {
-			case <-stop:
+			p, err := sub.Next(ctx)
+			if ctx.Err() != nil || errors.Is(err, dhcp.ErrClosed) {
 				log.Debug("stopping MAC sniffing")
 				return
-			default:
 			}
-
-			mac, xid, err := s.SniffMac()
 			if err != nil {
 				log.Errorf("failed to sniff MAC: %v", err)
 				continue
 			}
-			log.Debugf("new MAC: %v", mac)
-			info <- newDiscoverInfo(mac, xid)
-
-			// // Test Code
-			// log.Debug("sending test MAC")
-			// macs <- net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
-			// time.Sleep(5 * time.Second)
+			log.Debugf("new client: %v", dhcp.ClientHwAddr(p))
+			select {
+			case info <- newDiscoverInfo(p):
+			case <-ctx.Done():
+				log.Debug("stopping MAC sniffing")
+				return
+			}
 		}
 	}()
 	return info
@@ -209,11 +271,12 @@ type keyMap struct {
 
 	Help key.Binding
 
-	Quit key.Binding
+	Abort key.Binding
+	Quit  key.Binding
 }
 
 func (k keyMap) ShortHelp() []key.Binding {
-	return []key.Binding{k.Help, k.Quit}
+	return []key.Binding{k.Help, k.Abort, k.Quit}
 }
 
 func (k keyMap) FullHelp() [][]key.Binding {
@@ -243,8 +306,12 @@ var keys = keyMap{
 		key.WithKeys("?"),
 		key.WithHelp("?", "Toggle help"),
 	),
+	Abort: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "Abort"),
+	),
 	Quit: key.NewBinding(
-		key.WithKeys("q", "esc", "ctrl+c"),
+		key.WithKeys("q", "ctrl+c"),
 		key.WithHelp("q", "Quit"),
 	),
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/charmbracelet/bubbles/help"
@@ -23,7 +24,10 @@ type model struct {
 	server           *dhcp.Server
 	discoverChan     chan discoverInfo
 	selectedDiscover discoverInfo
-	stopChan         chan struct{}
+	stopSniff        context.CancelFunc
+
+	// abort cancels the assignment in progress, if any
+	abort context.CancelFunc
 
 	lModel listenModel
 
@@ -52,8 +56,6 @@ func newModel(cfg config, server *dhcp.Server) model {
 		cfg:    cfg,
 		server: server,
 
-		stopChan: make(chan struct{}),
-
 		lModel:   newListenModel(),
 		ipsetter: NewIPSetter(),
 
@@ -74,6 +76,7 @@ func (m model) Init() tea.Cmd {
 	return tea.Batch(
 		m.lModel.Init(),
 		m.ipsetter.Init(),
+		m.waitEvent(),
 	)
 }
 
@@ -85,17 +88,16 @@ func (m model) UpdateMACListener(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.state = 1
 		m.selectedDiscover = discoverInfo(msg)
 		log.Debug("selected MAC: ", m.selectedDiscover)
-		log.Debug("sending stop signal")
+		log.Debug("stopping sniffer")
+		m.ipsetter.SetClientID(m.selectedDiscover.id)
 		m.ipsetter.SetHwAddr(m.selectedDiscover.hwaddr)
 		m.ipsetter.SetTXID(m.selectedDiscover.xid)
-		go func() {
-			m.stopChan <- struct{}{}
-		}()
+		m.stopSniff()
 		return m, cmd
 	case discoverInfo:
 		log.Debug("msg: discoverInfo")
 		for i := range m.lModel.list {
-			if m.lModel.list[i].hwaddr.String() == msg.hwaddr.String() {
+			if m.lModel.list[i].id == msg.id {
 				m.lModel.list[i] = msg
 				return m, m.getMac()
 			}
@@ -107,40 +109,32 @@ func (m model) UpdateMACListener(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(cmd, m.getMac())
 }
 
-func (m model) sendOffer(req SetIPRequest) error {
-	m.ipsetter.Log("Sending offer")
-	err := m.server.Offer(req.MAC, req.IP, req.XID)
+// setIP runs the exchange that assigns req.IP. Its progress arrives as
+// dhcp.Event messages.
+func (m model) setIP(ctx context.Context, req SetIPRequest) error {
+	err := m.server.Assign(ctx, req.ID, req.MAC, req.IP, req.XID)
 	if err != nil {
 		err = fmt.Errorf("failed to set IP: %w", err)
 		m.ipsetter.Log(err.Error())
 		return err
 	}
-	m.ipsetter.Log("Offer sent successfully")
 	return nil
 }
 
-func (m model) waitRequest(req SetIPRequest) error {
-	m.ipsetter.Log("Listening for request from device")
-	err := m.server.WaitRequest(req.MAC, req.IP, req.XID)
-	if err != nil {
-		err = fmt.Errorf("failed to set IP: %w", err)
-		m.ipsetter.Log(err.Error())
-		return err
+// assignContext returns the context of an assignment, which Esc cancels,
+// limited to the configured timeout
+func (m model) assignContext() (context.Context, context.CancelFunc) {
+	if m.cfg.timeout > 0 {
+		return context.WithTimeout(context.Background(), m.cfg.timeout)
 	}
-	m.ipsetter.Log("Request received")
-	return nil
+	return context.WithCancel(context.Background())
 }
 
-func (m model) sendAck(req SetIPRequest) error {
-	m.ipsetter.Log("Sending ACK packet")
-	err := m.server.Ack(req.MAC, req.IP, req.XID)
-	if err != nil {
-		err = fmt.Errorf("failed to set IP: %w", err)
-		m.ipsetter.Log(err.Error())
-		return err
+// waitEvent delivers the next transaction event from the server
+func (m model) waitEvent() tea.Cmd {
+	return func() tea.Msg {
+		return <-m.server.Events()
 	}
-	m.ipsetter.Log("ACK sent successfully")
-	return nil
 }
 
 func (m model) UpdateIPInput(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -150,16 +144,11 @@ func (m model) UpdateIPInput(msg tea.Msg) (tea.Model, tea.Cmd) {
 		log.Debug("msg: SetIPRequest")
 		log.Debug("setting IP: ", "details", msg)
 		m.ipsetter.pendLog.Item(NewSetIPLogMsg(fmt.Sprintf("Sending Offer to %v", msg.MAC)))
+		ctx, cancel := m.assignContext()
+		m.abort = cancel
 		return m, func() tea.Msg {
-			err := m.sendOffer(msg)
-			if err != nil {
-				return SetIPResult{err}
-			}
-			err = m.waitRequest(msg)
-			if err != nil {
-				return SetIPResult{err}
-			}
-			err = m.sendAck(msg)
+			defer cancel()
+			err := m.setIP(ctx, msg)
 			if err != nil {
 				return SetIPResult{err}
 			}
@@ -190,9 +179,24 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	m, cmd = m.updateWindow(msg)
 
 	switch msg := msg.(type) {
+	case dhcp.Event:
+		log.Debug("msg: transaction event", "event", msg.String())
+		m.ipsetter.Log(msg.String())
+		if msg.State == dhcp.TxDeclined {
+			m.ipsetter.AddConflict(msg.IP)
+		}
+		return m, m.waitEvent()
+	case SetIPResult:
+		m.abort = nil
 	case tea.KeyMsg:
 		switch {
-		case key.Matches(msg, m.keys.Quit):
+		case key.Matches(msg, m.keys.Abort) && m.abort != nil:
+			log.Debug("aborting assignment")
+			m.ipsetter.Log("Aborting...")
+			m.abort()
+			m.abort = nil
+			return m, nil
+		case key.Matches(msg, m.keys.Quit), key.Matches(msg, m.keys.Abort):
 			return m, tea.Quit
 		case key.Matches(msg, m.keys.Help):
 			m.help.ShowAll = !m.help.ShowAll
@@ -0,0 +1,114 @@
+package dhcp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"github.com/jon-ski/dhcpset/pkg/dhcp/pkt"
+	"github.com/jon-ski/dhcpset/pkg/dhcp/pkt6"
+)
+
+// ClientID identifies a DHCP client. It holds the raw client identifier
+// (option 61): a type byte followed by the identifier. Clients without
+// option 61 are identified by their hardware type and address, which is
+// the same form as a hardware based option 61.
+//
+// ClientID is comparable and can be used as a map key.
+type ClientID string
+
+// Client identifier types
+const (
+	// clientIDTypeDUID marks an RFC 4361 identifier: IAID followed by a DUID
+	clientIDTypeDUID = 0xff
+)
+
+// ClientIDFromPkt returns the identity of the client that sent p,
+// preferring option 61 over the client hardware address
+func ClientIDFromPkt(p *pkt.Pkt) ClientID {
+	if data, ok := p.Options.GetBytes(pkt.OptionClientIdentifier); ok && len(data) > 1 {
+		return ClientID(data)
+	}
+	return NewHardwareClientID(p.Header.HType, HwAddrFromPkt(p))
+}
+
+// NewHardwareClientID creates a client ID from a hardware type and address
+func NewHardwareClientID(htype uint8, hwaddr net.HardwareAddr) ClientID {
+	return ClientID(append([]byte{htype}, hwaddr...))
+}
+
+// Type returns the client identifier type byte
+func (id ClientID) Type() uint8 {
+	if len(id) == 0 {
+		return 0
+	}
+	return id[0]
+}
+
+// IsDUID reports whether id is an RFC 4361 IAID/DUID identifier
+func (id ClientID) IsDUID() bool {
+	return id.Type() == clientIDTypeDUID && len(id) > 1+4+2
+}
+
+// IAID returns the identity association of an RFC 4361 identifier
+func (id ClientID) IAID() (uint32, bool) {
+	if !id.IsDUID() {
+		return 0, false
+	}
+	return binary.BigEndian.Uint32([]byte(id[1:5])), true
+}
+
+// DUID returns the DUID of an RFC 4361 identifier
+func (id ClientID) DUID() ([]byte, bool) {
+	if !id.IsDUID() {
+		return nil, false
+	}
+	return []byte(id[5:]), true
+}
+
+// HardwareAddr returns the link layer address in the identifier, if any.
+// For DUID-LL and DUID-LLT identifiers this is the address in the DUID.
+func (id ClientID) HardwareAddr() (net.HardwareAddr, bool) {
+	if len(id) < 2 {
+		return nil, false
+	}
+	duid, ok := id.DUID()
+	if !ok {
+		if id.Type() == 0 {
+			// type 0 identifiers are opaque, not hardware addresses
+			return nil, false
+		}
+		return net.HardwareAddr(id[1:]), true
+	}
+	return pkt6.DUID(duid).HardwareAddr()
+}
+
+func (id ClientID) String() string {
+	if len(id) == 0 {
+		return ""
+	}
+	if iaid, ok := id.IAID(); ok {
+		duid, _ := id.DUID()
+		return fmt.Sprintf("iaid %08x duid %x", iaid, duid)
+	}
+	if id.Type() == 0 {
+		return fmt.Sprintf("%x", []byte(id[1:]))
+	}
+	return net.HardwareAddr(id[1:]).String()
+}
+
+// HwAddrFromPkt returns the client hardware address using the length in the header
+func HwAddrFromPkt(p *pkt.Pkt) net.HardwareAddr {
+	return HwAddrFromBytes(p.Header.CHAddr[:], int(p.Header.HLen))
+}
+
+// ClientHwAddr returns the hardware address a client sent: chaddr, or for
+// hardware types that leave it empty, such as InfiniBand (RFC 4390), the
+// link-layer address in its client identifier
+func ClientHwAddr(p *pkt.Pkt) net.HardwareAddr {
+	if hwaddr := HwAddrFromPkt(p); len(hwaddr) > 0 {
+		return hwaddr
+	}
+	hwaddr, _ := ClientIDFromPkt(p).HardwareAddr()
+	return hwaddr
+}
@@ -0,0 +1,148 @@
+package dhcp
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+
+	"github.com/jon-ski/dhcpset/pkg/dhcp/pkt"
+)
+
+// ErrConflict is returned when assigning an address a client declined
+// because another device already uses it
+var ErrConflict = errors.New("address in use by another device")
+
+// serveClients answers the client messages that arrive outside the
+// OFFER and REQUEST exchange of Assign: REQUESTs from clients that
+// rebooted or renew their lease, DECLINEs, RELEASEs and INFORMs. It runs
+// until the server is closed.
+func (s *Server) serveClients() {
+	sub := s.Subscribe(Filter{Types: []pkt.MessageType{
+		pkt.MessageTypeRequest,
+		pkt.MessageTypeDecline,
+		pkt.MessageTypeRelease,
+		pkt.MessageTypeInform,
+	}})
+	defer sub.Close()
+	for p := range sub.C() {
+		var err error
+		switch p.MessageType() {
+		case pkt.MessageTypeRequest:
+			err = s.answerRequest(p)
+		case pkt.MessageTypeDecline:
+			s.handleDecline(p)
+		case pkt.MessageTypeRelease:
+			s.handleRelease(p)
+		case pkt.MessageTypeInform:
+			err = s.answerInform(p)
+		}
+		if err != nil {
+			slog.Warn("failed to answer client", "type", p.MessageType(),
+				"xid", fmt.Sprintf("0x%08x", p.Header.XID), "err", err)
+		}
+	}
+}
+
+// forUs reports whether a DECLINE or RELEASE names this server
+func (s *Server) forUs(p *pkt.Pkt) bool {
+	serverID, _ := p.Options.GetIP(pkt.OptionServerIdentifier)
+	return serverID.Equal(s.addr)
+}
+
+// track reports a message from the client id, starting a transaction for
+// ip if the server does not know it
+func (s *Server) track(p *pkt.Pkt, id ClientID, ip net.IP) {
+	if _, ok := s.Transaction(id, p.Header.XID); !ok {
+		s.begin(p, id, ip)
+	}
+}
+
+// handleDecline marks the address a client declined as in use, since the
+// client found another device with it (RFC 2131 section 4.3.3), and drops
+// the client's reservation of it
+func (s *Server) handleDecline(p *pkt.Pkt) {
+	if !s.forUs(p) {
+		return
+	}
+	ip, _ := p.Options.GetIP(pkt.OptionRequestedIPAddress)
+	id := ClientIDFromPkt(p)
+	s.mu.Lock()
+	s.conflicts[ip.String()] = id
+	if reserved, ok := s.reservations[id]; ok && reserved.Equal(ip) {
+		delete(s.reservations, id)
+	}
+	s.mu.Unlock()
+
+	reason := "address in use by another device"
+	if msg, ok := p.Options.GetString(pkt.OptionMessage); ok {
+		reason += ", " + msg
+	}
+	slog.Warn("client declined address", "ip", ip, "id", id, "reason", reason)
+	s.track(p, id, ip)
+	s.transition(id, p.Header.XID, TxDeclined, reason)
+}
+
+// Conflicted reports whether a client declined ip as in use
+func (s *Server) Conflicted(ip net.IP) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.conflicts[ip.String()]
+	return ok
+}
+
+// Conflicts returns the addresses clients declined as in use, sorted
+func (s *Server) Conflicts() []net.IP {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ips := make([]net.IP, 0, len(s.conflicts))
+	for ip := range s.conflicts {
+		ips = append(ips, net.ParseIP(ip))
+	}
+	slices.SortFunc(ips, func(a, b net.IP) int {
+		return slices.Compare(a.To16(), b.To16())
+	})
+	return ips
+}
+
+// handleRelease frees the reservation of a client giving up its address
+func (s *Server) handleRelease(p *pkt.Pkt) {
+	if !s.forUs(p) {
+		return
+	}
+	ip := IPv4(p.Header.CIAddr[:])
+	id := ClientIDFromPkt(p)
+	s.mu.Lock()
+	reserved, ok := s.reservations[id]
+	if ok && reserved.Equal(ip) {
+		delete(s.reservations, id)
+	}
+	s.mu.Unlock()
+	if !ok || !reserved.Equal(ip) {
+		slog.Debug("ignoring release of unknown address", "ip", ip, "id", id)
+		return
+	}
+	s.track(p, id, ip)
+	s.transition(id, p.Header.XID, TxReleased, "")
+}
+
+// newInformAck returns the DHCPACK answering req, a DHCPINFORM from a
+// client that configured its address itself. It holds configuration
+// options only: no yiaddr and no lease times (RFC 2131 section 4.3.5).
+func (s *Server) newInformAck(req *pkt.Pkt) *pkt.Pkt {
+	reply := pkt.NewReplyTo(req, pkt.MessageTypeAck)
+	s.addReplyOptions(reply, req)
+	for _, code := range dhcpOnlyOptions {
+		reply.Options.Delete(code)
+	}
+	return reply
+}
+
+// answerInform sends configuration options to a client that asks with a
+// DHCPINFORM. The reply goes to its ciaddr.
+func (s *Server) answerInform(req *pkt.Pkt) error {
+	p := s.newInformAck(req)
+	slog.Debug("answering inform", "ciaddr", IPv4(req.Header.CIAddr[:]), "packet", p)
+	return s.Write(p)
+}
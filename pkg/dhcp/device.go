@@ -23,12 +23,11 @@ type RequestData struct {
 func RequestDataFromPkt(pkt *pkt.Pkt) RequestData {
 	return RequestData{
 		ServerData: DeviceData{
-			HWAddr: HwAddrFromBytes(pkt.Header.CHAddr[:]),
-			IP:     IPv4(pkt.Header.CIAddr[:]),
+			IP: IPv4(pkt.Header.SIAddr[:]),
 		},
 		ClientData: DeviceData{
-			HWAddr: HwAddrFromBytes(pkt.Header.SIAddr[:]),
-			IP:     IPv4(pkt.Header.SIAddr[:]),
+			HWAddr: ClientHwAddr(pkt),
+			IP:     IPv4(pkt.Header.CIAddr[:]),
 		},
 		XID: pkt.Header.XID,
 	}
@@ -1,20 +1,86 @@
 package dhcp
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/jon-ski/dhcpset/pkg/dhcp/pkt"
 )
 
 var ErrInvalidIP = errors.New("invalid IP address")
 
+// readBufferSize fits any UDP datagram, since clients may advertise
+// a maximum message size above the ethernet MTU
+const readBufferSize = 65535
+
 type Server struct {
 	conn *net.UDPConn
 
 	addr net.IP
+
+	// options are the configured options sent to clients that ask for them
+	options pkt.Options
+
+	// vendorRules attach vendor specific information by vendor class
+	vendorRules []vendorRule
+
+	// requests holds the last packet received from each client, by XID,
+	// until it expires. Clients pick XIDs at random, so several clients
+	// may share one.
+	mu       sync.Mutex
+	requests map[uint32][]request
+	swept    time.Time
+
+	// reservations holds the address assigned to each client
+	reservations map[ClientID]net.IP
+
+	// conflicts holds the addresses clients declined as already in use,
+	// with the client that declined them
+	conflicts map[string]ClientID
+
+	// txs tracks the exchange with each client by XID and client
+	txs     map[txKey]*Transaction
+	events  chan Event
+	backoff Backoff
+
+	// capture receives a copy of every packet read or written
+	capture Capture
+
+	// subs receive packets from the receive loop, which closes done when
+	// it ends
+	subMu   sync.Mutex
+	subs    map[*Subscription]struct{}
+	closed  bool
+	readErr error
+	done    chan struct{}
+}
+
+// Capture receives a copy of every packet the server reads or writes,
+// such as a pcap.Writer
+type Capture interface {
+	WriteUDP(ts time.Time, src, dst *net.UDPAddr, payload []byte) error
+}
+
+// SetCapture records all traffic to c. It must be called before Listen.
+func (s *Server) SetCapture(c Capture) {
+	s.capture = c
+}
+
+func (s *Server) capturePacket(src, dst *net.UDPAddr, payload []byte) {
+	if s.capture == nil {
+		return
+	}
+	err := s.capture.WriteUDP(time.Now(), src, dst, payload)
+	if err != nil {
+		slog.Warn("failed to capture packet", "err", err)
+	}
 }
 
 func NewServer(ipAddr string) (*Server, error) {
@@ -22,9 +88,20 @@ func NewServer(ipAddr string) (*Server, error) {
 	if addr == nil {
 		return nil, ErrInvalidIP
 	}
-	return &Server{
-		addr: addr,
-	}, nil
+	s := &Server{
+		addr:         addr,
+		requests:     make(map[uint32][]request),
+		reservations: make(map[ClientID]net.IP),
+		conflicts:    make(map[string]ClientID),
+		txs:          make(map[txKey]*Transaction),
+		events:       make(chan Event, eventBuffer),
+		backoff:      DefaultBackoff,
+		subs:         make(map[*Subscription]struct{}),
+		done:         make(chan struct{}),
+	}
+	s.options.Add(pkt.NewOptionSubnetMask(net.IPv4Mask(255, 255, 255, 0)))
+	s.options.SetDuration(pkt.OptionIPAddressLeaseTime, pkt.InfiniteLease)
+	return s, nil
 }
 
 func (s *Server) Listen() error {
@@ -36,30 +113,148 @@ func (s *Server) Listen() error {
 	if err != nil {
 		return fmt.Errorf("failed to listen on UDP: %w", err)
 	}
+	go s.receive()
+	go s.serveClients()
 	return nil
 }
 
+// Close closes the socket and waits for the receive loop to close every
+// subscription
 func (l *Server) Close() error {
-	return l.conn.Close()
+	err := l.conn.Close()
+	<-l.done
+	return err
+}
+
+// Read returns the next packet. Packets that arrive while nobody is
+// waiting are dropped, use Subscribe to receive all of them. It returns
+// ErrTimeout when the deadline of ctx passes and the context error when
+// it is canceled.
+func (l *Server) Read(ctx context.Context) (*pkt.Pkt, error) {
+	sub := l.Subscribe(Filter{})
+	defer sub.Close()
+	return sub.Next(ctx)
 }
 
-func (l *Server) Read() (*pkt.Pkt, error) {
-	slog.Debug("reading packet")
-	buf := make([]byte, 1500)
-	n, _, err := l.conn.ReadFromUDP(buf)
+// Sniff waits for a DHCPDISCOVER or a BOOTP request and returns it
+func (s *Server) Sniff(ctx context.Context) (*pkt.Pkt, error) {
+	sub := s.Subscribe(DiscoverFilter())
+	defer sub.Close()
+	p, err := sub.Next(ctx)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to read packet: %w", err)
 	}
-	return pkt.NewFromBytes(buf[:n])
+	slog.Debug("sniffed client",
+		"htype", p.Header.HType, "hwaddr", ClientHwAddr(p), "id", ClientIDFromPkt(p))
+	return p, nil
 }
 
-func (s *Server) SniffMac() (net.HardwareAddr, uint32, error) {
-	pkt, err := s.Read()
+// SniffMac waits for a DHCPDISCOVER and returns the client hardware
+// address, of any length, and transaction ID
+func (s *Server) SniffMac(ctx context.Context) (net.HardwareAddr, uint32, error) {
+	p, err := s.Sniff(ctx)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to read packet: %w", err)
+		return nil, 0, err
+	}
+	return ClientHwAddr(p), p.Header.XID, nil
+}
+
+// request is a packet received from a client and when it arrived
+type request struct {
+	p    *pkt.Pkt
+	id   ClientID
+	seen time.Time
+}
+
+// remember stores the last packet received from a client and expires old
+// ones
+func (s *Server) remember(p *pkt.Pkt) {
+	now := time.Now()
+	r := request{p: p, id: ClientIDFromPkt(p), seen: now}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rs := s.requests[p.Header.XID]
+	i := slices.IndexFunc(rs, func(old request) bool { return old.id == r.id })
+	if i < 0 {
+		rs = append(rs, r)
+	} else {
+		rs[i] = r
+	}
+	s.requests[p.Header.XID] = rs
+	s.expire(now)
+}
+
+// forget drops the last packet of the client id in transaction xid.
+// s.mu must be held.
+func (s *Server) forget(id ClientID, xid uint32) {
+	rs := slices.DeleteFunc(s.requests[xid], func(r request) bool { return r.id == id })
+	if len(rs) == 0 {
+		delete(s.requests, xid)
+		return
+	}
+	s.requests[xid] = rs
+}
+
+// Reserve records the address assigned to a client
+func (s *Server) Reserve(id ClientID, ip net.IP) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reservations[id] = ip
+}
+
+// Reservation returns the address assigned to a client
+func (s *Server) Reservation(id ClientID) (net.IP, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ip, ok := s.reservations[id]
+	return ip, ok
+}
+
+// IsBOOTP reports whether a transaction is from a plain BOOTP client,
+// which is answered with BootReply instead of an OFFER and ACK
+func (s *Server) IsBOOTP(hwAddr net.HardwareAddr, xid uint32) bool {
+	p, ok := s.lastRequest(hwAddr, xid)
+	return ok && p.IsBOOTP()
+}
+
+// lastRequest returns the last packet the client with hardware address
+// hwAddr sent in transaction xid
+func (s *Server) lastRequest(hwAddr net.HardwareAddr, xid uint32) (*pkt.Pkt, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.requests[xid] {
+		if bytes.Equal(ClientHwAddr(r.p), hwAddr) {
+			return r.p, true
+		}
+	}
+	return nil, false
+}
+
+// ctxErr returns the error for a done context: ErrTimeout, which also
+// matches context.DeadlineExceeded, or context.Canceled
+func ctxErr(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	}
+	return err
+}
+
+// skipMalformed logs and reports whether err is a decoding or validation
+// error that should be skipped rather than returned
+func skipMalformed(err error) bool {
+	var perr *pkt.ParseError
+	if errors.As(err, &perr) {
+		slog.Warn("skipping malformed packet", "offset", perr.Offset, "err", perr.Err)
+		return true
 	}
-	slog.Debug("sniffed MAC address", "mac", pkt.Header.CHAddr[:6])
-	return pkt.Header.CHAddr[:6], pkt.Header.XID, nil
+	var verrs pkt.ValidationErrors
+	if errors.As(err, &verrs) {
+		for _, verr := range verrs {
+			slog.Warn("skipping invalid packet", "rule", verr.Rule, "option", verr.Option, "err", verr.Msg)
+		}
+		return true
+	}
+	return false
 }
 
 func (l *Server) Write(pkt *pkt.Pkt) error {
@@ -68,27 +263,31 @@ func (l *Server) Write(pkt *pkt.Pkt) error {
 	if err != nil {
 		return fmt.Errorf("failed to marshal packet: %w", err)
 	}
-	_, err = l.conn.WriteToUDP(buf, &net.UDPAddr{
-		Port: 68,
-		IP:   net.IPv4bcast,
-	})
+	dst := pkt.ReplyAddr()
+	_, err = l.conn.WriteToUDP(buf, dst)
 	if err != nil {
 		return fmt.Errorf("failed to write packet: %w", err)
 	}
+	l.capturePacket(&net.UDPAddr{IP: l.addr, Port: 67}, dst, buf)
 	return nil
 }
 
+// requestFor returns the last packet hwAddr sent in a transaction. If
+// none was seen, it stands in a DHCPDISCOVER from hwAddr.
+func (s *Server) requestFor(hwAddr net.HardwareAddr, xid uint32) *pkt.Pkt {
+	if req, ok := s.lastRequest(hwAddr, xid); ok {
+		return req
+	}
+	return pkt.NewDiscover(hwAddr, xid)
+}
+
 func (s *Server) newOffer(hwAddr net.HardwareAddr, ip net.IP, xid uint32) *pkt.Pkt {
-	req := s.newPkt()
-	req.Header.OpCode = 0x02
-	req.Header.XID = xid
-	req.Header.YIAddr = [4]byte(ip.To4())
-	req.SetCHAddr(hwAddr)
-	req.Options.Add(pkt.NewOptionMessageType(pkt.MessageTypeOffer))
-	req.Options.Add(pkt.NewOptionServerID(s.addr.To4()))
-	req.Options.Add(pkt.NewOptionSubnetMask(net.IPv4Mask(255, 255, 255, 0)))
-	req.Options.Add(pkt.NewOptionEnd())
-	return req
+	req := s.requestFor(hwAddr, xid)
+	reply := pkt.NewReplyTo(req, pkt.MessageTypeOffer)
+	reply.Header.YIAddr = [4]byte(ip.To4())
+	reply.Header.SIAddr = [4]byte(s.addr.To4())
+	s.addReplyOptions(reply, req)
+	return reply
 }
 
 func (l *Server) Offer(hwAddr net.HardwareAddr, ip net.IP, xid uint32) error {
@@ -97,71 +296,61 @@ func (l *Server) Offer(hwAddr net.HardwareAddr, ip net.IP, xid uint32) error {
 	return l.Write(pkt)
 }
 
-func (s *Server) newAck(hwAddr net.HardwareAddr, ip net.IP, xid uint32) *pkt.Pkt {
-	const opCode = 0x02 // Ack
-	const htype = 0x01  // Ethernet
-
-	req := s.newPkt()
-	req.Header.OpCode = opCode
-	req.Header.XID = xid
-	req.Header.YIAddr = [4]byte(ip.To4())
-	req.Header.SIAddr = [4]byte(s.addr.To4())
-	req.SetCHAddr(hwAddr)
-	req.Options.Add(pkt.NewOptionMessageType(pkt.MessageTypeAck))
-	req.Options.Add(pkt.NewOptionServerID(s.addr.To4()))
-	req.Options.Add(pkt.NewOptionSubnetMask(net.IPv4Mask(255, 255, 255, 0)))
-	req.Options.Add(pkt.NewOptionEnd())
-	return req
-}
-
-func (s *Server) WaitRequest(hwAddr net.HardwareAddr, ip net.IP, xid uint32) error {
-	// Read until we see the request
-	const opCode = 0x01 // Request
-	slog.Debug("listening for request")
-	for {
-		pkt, err := s.Read()
-		if err != nil {
-			return fmt.Errorf("failed to read packet: %w", err)
-		}
-		slog.Debug("received packet", "packet", pkt)
-		if pkt.Header.OpCode == opCode && pkt.Header.XID == xid {
-			slog.Debug("received request", "packet", pkt)
-			break
-		}
+func (s *Server) newBootReply(hwAddr net.HardwareAddr, ip net.IP, xid uint32) *pkt.Pkt {
+	req, ok := s.lastRequest(hwAddr, xid)
+	if !ok {
+		req = pkt.NewBOOTPRequest(hwAddr, xid)
 	}
-	return nil
+	reply := pkt.NewBOOTPReply(req)
+	reply.Header.YIAddr = [4]byte(ip.To4())
+	reply.Header.SIAddr = [4]byte(s.addr.To4())
+	if reply.Vendor == nil {
+		s.addBOOTPOptions(reply, req)
+	}
+	return reply
 }
 
-func (s *Server) Ack(hwAddr net.HardwareAddr, ip net.IP, xid uint32) error {
-	// Send the ACK
-	slog.Debug("creating ack")
-	pkt := s.newAck(hwAddr, ip, xid)
-	slog.Debug("sending ack", "packet", pkt)
-	return s.Write(pkt)
+// BootReply answers a BOOTP client with its address. BOOTP has no
+// further exchange, the client is configured once the reply arrives.
+func (s *Server) BootReply(hwAddr net.HardwareAddr, ip net.IP, xid uint32) error {
+	p := s.newBootReply(hwAddr, ip, xid)
+	slog.Debug("sending bootreply", "packet", p)
+	return s.Write(p)
 }
 
-func (s *Server) OfferRequest(hwAddr net.HardwareAddr, ip net.IP, xid uint32) error {
-	err := s.Offer(hwAddr, ip, xid)
-	if err != nil {
-		return fmt.Errorf("failed to send offer: %w", err)
-	}
-	slog.Debug("Offer sent")
+func (s *Server) newAck(hwAddr net.HardwareAddr, ip net.IP, xid uint32) *pkt.Pkt {
+	req := s.requestFor(hwAddr, xid)
+	reply := pkt.NewReplyTo(req, pkt.MessageTypeAck)
+	reply.Header.YIAddr = [4]byte(ip.To4())
+	reply.Header.SIAddr = [4]byte(s.addr.To4())
+	s.addReplyOptions(reply, req)
+	return reply
+}
 
-	// Read until we see the request
-	const opCode = 0x01 // Request
-	slog.Debug("listening for request")
-	for {
-		pkt, err := s.Read()
-		if err != nil {
-			return fmt.Errorf("failed to read packet: %w", err)
-		}
-		slog.Debug("received packet", "packet", pkt)
-		if pkt.Header.OpCode == opCode && pkt.Header.XID == xid {
-			slog.Debug("received request", "packet", pkt)
-			break
-		}
+// WaitRequest waits for the REQUEST of transaction xid from the client
+// with hardware address hwAddr and checks that it accepts ip from this
+// server, see Assign for the errors. A REQUEST sent before the call is
+// missed, Assign subscribes before sending the OFFER.
+func (s *Server) WaitRequest(
+	ctx context.Context, hwAddr net.HardwareAddr, ip net.IP, xid uint32,
+) error {
+	sub := s.Subscribe(requestFilter(xid))
+	defer sub.Close()
+	id := ClientIDFromPkt(s.requestFor(hwAddr, xid))
+	_, err := s.awaitRequest(ctx, sub, id, hwAddr, ip)
+	return err
+}
+
+// requestFilter matches the REQUESTs of transaction xid
+func requestFilter(xid uint32) Filter {
+	return Filter{
+		Types:    []pkt.MessageType{pkt.MessageTypeRequest},
+		XID:      xid,
+		MatchXID: true,
 	}
+}
 
+func (s *Server) Ack(hwAddr net.HardwareAddr, ip net.IP, xid uint32) error {
 	// Send the ACK
 	slog.Debug("creating ack")
 	pkt := s.newAck(hwAddr, ip, xid)
@@ -169,27 +358,13 @@ func (s *Server) OfferRequest(hwAddr net.HardwareAddr, ip net.IP, xid uint32) er
 	return s.Write(pkt)
 }
 
-func (s *Server) newPkt() *pkt.Pkt {
-	return &pkt.Pkt{
-		Header: pkt.Header{
-			OpCode: 0x00,
-			HType:  0,
-			HLen:   0,
-			Hops:   0,
-			XID:    0,
-			Secs:   0,
-			Flags:  0,
-			CIAddr: [4]byte{},
-			YIAddr: [4]byte{},
-			SIAddr: [4]byte(s.addr.To4()),
-			GIAddr: [4]byte{},
-			CHAddr: [16]byte{},
-			SName:  [64]byte{},
-			File:   [128]byte{},
-			Cookie: [4]byte{0x63, 0x82, 0x53, 0x63},
-		},
-		Options: pkt.Options{},
-	}
+// OfferRequest runs the whole exchange with a client, see Assign. The
+// client is identified by the last packet hwAddr sent in transaction xid.
+func (s *Server) OfferRequest(
+	ctx context.Context, hwAddr net.HardwareAddr, ip net.IP, xid uint32,
+) error {
+	id := ClientIDFromPkt(s.requestFor(hwAddr, xid))
+	return s.Assign(ctx, id, hwAddr, ip, xid)
 }
 
 func (s *Server) ServeAddress() string {
@@ -0,0 +1,74 @@
+package dhcp
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/jon-ski/dhcpset/pkg/dhcp/pkt"
+)
+
+var (
+	hwAddr1 = net.HardwareAddr{0x00, 0x01, 0x01, 0x01, 0x01, 0x01}
+	hwAddr2 = net.HardwareAddr{0x00, 0x02, 0x02, 0x02, 0x02, 0x02}
+)
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	s, err := NewServer("192.168.0.1")
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	return s
+}
+
+func TestClientsSharingXID(t *testing.T) {
+	const xid = 0x42
+	s := newTestServer(t)
+	d1, d2 := pkt.NewDiscover(hwAddr1, xid), pkt.NewDiscover(hwAddr2, xid)
+	d2.Options.Set(pkt.NewOption(pkt.OptionHostName, []byte("second")))
+	s.remember(d1)
+	s.remember(d2)
+
+	for _, hw := range []net.HardwareAddr{hwAddr1, hwAddr2} {
+		offer := s.newOffer(hw, net.IPv4(192, 168, 0, 10), xid)
+		if got := ClientHwAddr(offer); !bytes.Equal(got, hw) {
+			t.Errorf("offer for %v has chaddr %v", hw, got)
+		}
+		req := s.requestFor(hw, xid)
+		if got := ClientHwAddr(req); !bytes.Equal(got, hw) {
+			t.Errorf("request for %v is from %v", hw, got)
+		}
+	}
+
+	id1, id2 := ClientIDFromPkt(d1), ClientIDFromPkt(d2)
+	s.begin(d1, id1, net.IPv4(192, 168, 0, 10))
+	s.begin(d2, id2, net.IPv4(192, 168, 0, 11))
+	s.transition(id1, xid, TxAcked, "")
+	tx1, ok1 := s.Transaction(id1, xid)
+	tx2, ok2 := s.Transaction(id2, xid)
+	if !ok1 || !ok2 {
+		t.Fatalf("transactions missing: %v %v", ok1, ok2)
+	}
+	if tx1.State != TxAcked || tx2.State != TxInit {
+		t.Errorf("got states %v and %v, want %v and %v", tx1.State, tx2.State, TxAcked, TxInit)
+	}
+	if !tx2.IP.Equal(net.IPv4(192, 168, 0, 11)) {
+		t.Errorf("second transaction has ip %v", tx2.IP)
+	}
+
+	ev := <-s.Events()
+	if ev.ClientID != id1 || ev.Prev != TxInit || ev.Prev.String() != "init" {
+		t.Errorf("got event %+v", ev)
+	}
+
+	s.mu.Lock()
+	s.forget(id1, xid)
+	s.mu.Unlock()
+	if _, ok := s.lastRequest(hwAddr1, xid); ok {
+		t.Error("forgotten request still known")
+	}
+	if _, ok := s.lastRequest(hwAddr2, xid); !ok {
+		t.Error("forgot the request of the other client")
+	}
+}
@@ -0,0 +1,216 @@
+package dhcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+
+	"github.com/jon-ski/dhcpset/pkg/dhcp/pkt"
+)
+
+// ErrClosed is returned when waiting for packets on a closed server
+var ErrClosed = errors.New("server closed")
+
+// subBuffer is the number of packets kept for a slow subscriber
+const subBuffer = 16
+
+// Filter selects the packets a subscription receives. Unset fields match
+// any packet, so the zero Filter receives everything.
+type Filter struct {
+	// Types are the DHCP message types to receive
+	Types []pkt.MessageType
+	// BOOTP receives plain BOOTP requests. If neither Types nor BOOTP is
+	// set, packets of any kind match.
+	BOOTP bool
+	// XID matches one transaction if MatchXID is set. Zero is a valid
+	// transaction ID.
+	XID      uint32
+	MatchXID bool
+	// ClientID matches one client
+	ClientID ClientID
+}
+
+// DiscoverFilter matches the packets of clients looking for an address:
+// DHCPDISCOVERs and BOOTP requests
+func DiscoverFilter() Filter {
+	return Filter{
+		Types: []pkt.MessageType{pkt.MessageTypeDiscover},
+		BOOTP: true,
+	}
+}
+
+// matchView checks the fields of f that do not need the packet decoded
+func (f Filter) matchView(v pkt.PktView) bool {
+	if f.MatchXID && v.XID() != f.XID {
+		return false
+	}
+	if len(f.Types) == 0 && !f.BOOTP {
+		return true
+	}
+	if v.IsBOOTP() {
+		return f.BOOTP && v.OpCode() == pkt.OpCodeBootRequest
+	}
+	return slices.ContainsFunc(f.Types, v.Is)
+}
+
+// matchPkt checks the fields of f that need the decoded packet
+func (f Filter) matchPkt(p *pkt.Pkt) bool {
+	return f.ClientID == "" || ClientIDFromPkt(p) == f.ClientID
+}
+
+// Subscription receives the packets matching a Filter from the server's
+// receive loop until it is closed
+type Subscription struct {
+	s      *Server
+	filter Filter
+	c      chan *pkt.Pkt
+}
+
+// Subscribe starts delivering the packets matching f. Packets are dropped
+// when the subscriber falls behind. Close the subscription when done.
+func (s *Server) Subscribe(f Filter) *Subscription {
+	sub := &Subscription{
+		s:      s,
+		filter: f,
+		c:      make(chan *pkt.Pkt, subBuffer),
+	}
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+	if s.closed {
+		close(sub.c)
+		return sub
+	}
+	s.subs[sub] = struct{}{}
+	return sub
+}
+
+// C returns the channel packets are delivered on. It is closed when the
+// subscription or the server is closed.
+func (sub *Subscription) C() <-chan *pkt.Pkt {
+	return sub.c
+}
+
+// Next waits for the next packet. It returns ErrTimeout when the deadline
+// of ctx passes, the context error when it is canceled and ErrClosed once
+// the subscription or the server is closed.
+func (sub *Subscription) Next(ctx context.Context) (*pkt.Pkt, error) {
+	select {
+	case p, ok := <-sub.c:
+		if !ok {
+			return nil, sub.s.err()
+		}
+		return p, nil
+	case <-ctx.Done():
+		return nil, ctxErr(ctx.Err())
+	}
+}
+
+// Close stops delivery and closes the channel. It may be called more
+// than once.
+func (sub *Subscription) Close() {
+	s := sub.s
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+	if _, ok := s.subs[sub]; !ok {
+		return
+	}
+	delete(s.subs, sub)
+	close(sub.c)
+}
+
+// receive is the only reader of the socket. It dispatches every packet to
+// the matching subscriptions until the socket is closed, then closes them.
+func (s *Server) receive() {
+	defer close(s.done)
+	buf := make([]byte, readBufferSize)
+	for {
+		n, src, err := s.conn.ReadFromUDP(buf)
+		if err != nil {
+			s.stop(err)
+			return
+		}
+		// the destination is not known, clients normally broadcast
+		s.capturePacket(src, &net.UDPAddr{IP: net.IPv4bcast, Port: 67}, buf[:n])
+		err = s.dispatch(buf[:n])
+		if err != nil && !skipMalformed(err) {
+			slog.Warn("dropping packet", "src", src, "err", err)
+		}
+	}
+}
+
+// dispatch decodes b if a subscription wants it and delivers it. Packets
+// nobody matches are dropped without being decoded. Packets from clients
+// are remembered by XID.
+func (s *Server) dispatch(b []byte) error {
+	v, err := pkt.NewView(b)
+	if err != nil {
+		return err
+	}
+
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+	var subs []*Subscription
+	for sub := range s.subs {
+		if sub.filter.matchView(v) {
+			subs = append(subs, sub)
+		}
+	}
+	if len(subs) == 0 {
+		slog.Debug("ignoring packet", "type", v.MessageType(), "xid", v.XID())
+		return nil
+	}
+
+	p, err := v.Pkt()
+	if err != nil {
+		return err
+	}
+	err = p.Validate()
+	if err != nil {
+		return err
+	}
+	if p.Header.OpCode == pkt.OpCodeBootRequest {
+		s.remember(p)
+	}
+	for _, sub := range subs {
+		if !sub.filter.matchPkt(p) {
+			continue
+		}
+		select {
+		case sub.c <- p:
+		default:
+			slog.Warn("dropping packet for slow subscriber", "type", p.MessageType(), "xid", p.Header.XID)
+		}
+	}
+	return nil
+}
+
+// stop closes every subscription after the receive loop ends
+func (s *Server) stop(err error) {
+	if !errors.Is(err, net.ErrClosed) {
+		slog.Error("failed to read packet", "err", err)
+		err = fmt.Errorf("%w: %w", ErrClosed, err)
+	} else {
+		err = ErrClosed
+	}
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+	s.closed = true
+	s.readErr = err
+	for sub := range s.subs {
+		close(sub.c)
+	}
+	clear(s.subs)
+}
+
+// err returns why the receive loop ended
+func (s *Server) err() error {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+	if s.readErr == nil {
+		return ErrClosed
+	}
+	return s.readErr
+}
@@ -1,8 +1,13 @@
 package dhcp
 
-func HwAddrFromBytes(b []byte) []byte {
-	if len(b) < 6 {
+import "net"
+
+// HwAddrFromBytes returns a copy of the first n bytes of b, such as the
+// chaddr field and its hlen
+func HwAddrFromBytes(b []byte, n int) net.HardwareAddr {
+	n = min(n, len(b))
+	if n <= 0 {
 		return nil
 	}
-	return b[:6]
+	return net.HardwareAddr(append([]byte(nil), b[:n]...))
 }
@@ -0,0 +1,65 @@
+package pkt
+
+import "net"
+
+// VendorLen is the size of the BOOTP vendor area (RFC 951)
+const VendorLen = 64
+
+// bootpMinLen is the smallest BOOTP message, a header with a full vendor
+// area. Some BOOTP clients drop anything shorter (RFC 1542 section 2.1).
+const bootpMinLen = cookieOffset + VendorLen
+
+// IsBOOTP reports whether p is plain BOOTP, without a DHCP message type
+func (p *Pkt) IsBOOTP() bool {
+	return p.Vendor != nil || !p.Options.Has(OptionDHCPMessageType)
+}
+
+// padBOOTP pads b with zeros to the minimum BOOTP message size
+func padBOOTP(b []byte) []byte {
+	if len(b) >= bootpMinLen {
+		return b
+	}
+	return append(b, make([]byte, bootpMinLen-len(b))...)
+}
+
+// NewBOOTPRequest returns a BOOTREQUEST from the client with hardware
+// address hwAddr. It carries RFC 1048 vendor extensions: the magic cookie
+// followed by End. Set Vendor instead for clients without extensions.
+func NewBOOTPRequest(hwAddr net.HardwareAddr, xid uint32) *Pkt {
+	p := &Pkt{
+		Header: Header{
+			OpCode: OpCodeBootRequest,
+			XID:    xid,
+			Cookie: [4]byte(dhcpMagicCookie),
+		},
+	}
+	p.SetHardwareAddr(HTypeEthernet, hwAddr)
+	p.Options.Add(NewOptionEnd())
+	return p
+}
+
+// NewBOOTPReply returns the BOOTREPLY answering req, copying xid, flags,
+// ciaddr, giaddr, chaddr, htype and hlen. If req has RFC 1048 vendor
+// extensions the reply has them too and callers add options, otherwise
+// the reply has an empty vendor area. Callers set yiaddr and siaddr.
+func NewBOOTPReply(req *Pkt) *Pkt {
+	p := &Pkt{
+		Header: Header{
+			OpCode: OpCodeBootReply,
+			HType:  req.Header.HType,
+			HLen:   req.Header.HLen,
+			XID:    req.Header.XID,
+			Flags:  req.Header.Flags,
+			CIAddr: req.Header.CIAddr,
+			GIAddr: req.Header.GIAddr,
+			CHAddr: req.Header.CHAddr,
+		},
+	}
+	if req.Vendor != nil {
+		p.Vendor = make([]byte, VendorLen)
+		return p
+	}
+	p.Header.Cookie = [4]byte(dhcpMagicCookie)
+	p.MaxSize = DefaultMaxSize
+	return p
+}
@@ -0,0 +1,95 @@
+package pkt
+
+import (
+	"fmt"
+	"net"
+)
+
+// OptionClasslessStaticRoute is the classless static route option (RFC 3442)
+const OptionClasslessStaticRoute OptionCode = 121
+
+func init() {
+	registerOptions(OptionInfo{OptionClasslessStaticRoute, "Classless Static Route", KindClasslessRoutes})
+}
+
+// Route is a single classless static route
+type Route struct {
+	Dest   net.IPNet
+	Router net.IP
+}
+
+func (r Route) String() string {
+	return fmt.Sprintf("%v via %v", &r.Dest, r.Router)
+}
+
+// ParseClasslessRoutes decodes the data of option 121. Each route is a
+// prefix width, the significant octets of the destination and the router.
+func ParseClasslessRoutes(data []byte) ([]Route, error) {
+	var routes []Route
+	for i := 0; i < len(data); {
+		width := int(data[i])
+		if width > 32 {
+			return nil, parseErr(i, ErrBadOptionLength)
+		}
+		octets := (width + 7) / 8
+		if i+1+octets+net.IPv4len > len(data) {
+			return nil, parseErr(i, ErrTruncated)
+		}
+		dest := make(net.IP, net.IPv4len)
+		copy(dest, data[i+1:i+1+octets])
+		i += 1 + octets
+		router := net.IPv4(data[i], data[i+1], data[i+2], data[i+3])
+		i += net.IPv4len
+		routes = append(routes, Route{
+			Dest: net.IPNet{
+				IP:   dest,
+				Mask: net.CIDRMask(width, 32),
+			},
+			Router: router,
+		})
+	}
+	return routes, nil
+}
+
+// EncodeClasslessRoutes encodes routes as the data of option 121
+func EncodeClasslessRoutes(routes []Route) ([]byte, error) {
+	var buf []byte
+	for _, r := range routes {
+		dest := r.Dest.IP.To4()
+		router := r.Router.To4()
+		if dest == nil || router == nil {
+			return nil, fmt.Errorf("route %v is not IPv4", r)
+		}
+		width, bits := r.Dest.Mask.Size()
+		if bits != 32 {
+			return nil, fmt.Errorf("route %v has an invalid mask", r)
+		}
+		octets := (width + 7) / 8
+		buf = append(buf, byte(width))
+		buf = append(buf, dest.Mask(r.Dest.Mask)[:octets]...)
+		buf = append(buf, router...)
+	}
+	return buf, nil
+}
+
+// NewOptionClasslessRoutes creates option 121 from a list of routes
+func NewOptionClasslessRoutes(routes []Route) (Option, error) {
+	data, err := EncodeClasslessRoutes(routes)
+	if err != nil {
+		return Option{}, err
+	}
+	return NewOption(OptionClasslessStaticRoute, data), nil
+}
+
+// GetClasslessRoutes returns the decoded option 121
+func (o *Options) GetClasslessRoutes() ([]Route, bool) {
+	data, ok := o.GetBytes(OptionClasslessStaticRoute)
+	if !ok {
+		return nil, false
+	}
+	routes, err := ParseClasslessRoutes(data)
+	if err != nil {
+		return nil, false
+	}
+	return routes, true
+}
@@ -1 +1,50 @@
 package pkt
+
+import (
+	"fmt"
+	"net"
+)
+
+// newClientPkt returns a BOOTREQUEST of the given message type from an
+// ethernet client, ending with the End option. Further options can be
+// added with Options.Set and other hardware types set with SetHardwareAddr.
+func newClientPkt(t MessageType, hwAddr net.HardwareAddr, xid uint32) *Pkt {
+	p := &Pkt{
+		Header: Header{
+			OpCode: OpCodeBootRequest,
+			XID:    xid,
+			Cookie: [4]byte(dhcpMagicCookie),
+		},
+	}
+	p.SetHardwareAddr(HTypeEthernet, hwAddr)
+	p.Options.Add(NewOptionMessageType(t))
+	p.Options.Add(NewOptionEnd())
+	return p
+}
+
+// NewDiscover returns a DHCPDISCOVER from the client with hardware
+// address hwAddr
+func NewDiscover(hwAddr net.HardwareAddr, xid uint32) *Pkt {
+	return newClientPkt(MessageTypeDiscover, hwAddr, xid)
+}
+
+// NewRequest returns the DHCPREQUEST a client in SELECTING state sends to
+// accept offer: it requests the offered address from the offering server
+func NewRequest(offer *Pkt) (*Pkt, error) {
+	if !offer.Is(MessageTypeOffer) {
+		return nil, fmt.Errorf("%w: not a DHCPOFFER", ErrInvalidPacket)
+	}
+	serverID, ok := offer.Options.GetIP(OptionServerIdentifier)
+	if !ok {
+		return nil, fmt.Errorf("%w: offer has no server identifier", ErrInvalidPacket)
+	}
+	p := newClientPkt(MessageTypeRequest, nil, offer.Header.XID)
+	p.Header.HType = offer.Header.HType
+	p.Header.HLen = offer.Header.HLen
+	p.Header.Flags = offer.Header.Flags
+	p.Header.CHAddr = offer.Header.CHAddr
+	yiaddr := offer.Header.YIAddr
+	p.Options.SetIP(OptionRequestedIPAddress, yiaddr[:])
+	p.Options.SetIP(OptionServerIdentifier, serverID)
+	return p, nil
+}
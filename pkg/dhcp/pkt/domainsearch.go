@@ -0,0 +1,127 @@
+package pkt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OptionDomainSearch is the domain search list option (RFC 3397)
+const OptionDomainSearch OptionCode = 119
+
+func init() {
+	registerOptions(OptionInfo{OptionDomainSearch, "Domain Search", KindDomainList})
+}
+
+// maxLabelLen is the longest label allowed in a DNS name
+const maxLabelLen = 63
+
+// EncodeDomainSearch encodes a list of domain names as the data of
+// option 119, compressing repeated suffixes as described in RFC 1035
+func EncodeDomainSearch(domains []string) ([]byte, error) {
+	var buf []byte
+	// offsets of suffixes already written, for compression pointers
+	suffixes := make(map[string]int)
+	for _, domain := range domains {
+		labels := strings.Split(strings.TrimSuffix(domain, "."), ".")
+		for i := range labels {
+			suffix := strings.ToLower(strings.Join(labels[i:], "."))
+			if off, ok := suffixes[suffix]; ok {
+				buf = append(buf, 0xc0|byte(off>>8), byte(off))
+				break
+			}
+			label := labels[i]
+			if label == "" || len(label) > maxLabelLen {
+				return nil, fmt.Errorf("invalid domain name %q", domain)
+			}
+			// pointers hold offsets up to 0x3fff
+			if len(buf) <= 0x3fff {
+				suffixes[suffix] = len(buf)
+			}
+			buf = append(buf, byte(len(label)))
+			buf = append(buf, label...)
+			if i == len(labels)-1 {
+				buf = append(buf, 0)
+			}
+		}
+	}
+	return buf, nil
+}
+
+// ParseDomainSearch decodes the data of option 119
+func ParseDomainSearch(data []byte) ([]string, error) {
+	var domains []string
+	for i := 0; i < len(data); {
+		name, n, err := readDomainName(data, i)
+		if err != nil {
+			return nil, err
+		}
+		domains = append(domains, name)
+		i += n
+	}
+	return domains, nil
+}
+
+// readDomainName reads a possibly compressed name at offset off and
+// returns it with the number of bytes it occupies at that offset
+func readDomainName(data []byte, off int) (string, int, error) {
+	var labels []string
+	length := -1
+	// pointers must point backwards, which also rules out loops
+	limit := off
+	for i := off; ; {
+		if i >= len(data) {
+			return "", 0, parseErr(i, ErrTruncated)
+		}
+		n := int(data[i])
+		switch {
+		case n == 0:
+			if length < 0 {
+				length = i + 1 - off
+			}
+			return strings.Join(labels, "."), length, nil
+		case n&0xc0 == 0xc0:
+			if i+1 >= len(data) {
+				return "", 0, parseErr(i, ErrTruncated)
+			}
+			ptr := (n&0x3f)<<8 | int(data[i+1])
+			if ptr >= limit {
+				return "", 0, parseErr(i, ErrBadOptionLength)
+			}
+			if length < 0 {
+				length = i + 2 - off
+			}
+			limit = ptr
+			i = ptr
+		case n > maxLabelLen:
+			return "", 0, parseErr(i, ErrBadOptionLength)
+		default:
+			if i+1+n > len(data) {
+				return "", 0, parseErr(i, ErrTruncated)
+			}
+			labels = append(labels, string(data[i+1:i+1+n]))
+			i += 1 + n
+		}
+	}
+}
+
+// NewOptionDomainSearch creates option 119 from a list of domain names
+func NewOptionDomainSearch(domains []string) (Option, error) {
+	data, err := EncodeDomainSearch(domains)
+	if err != nil {
+		return Option{}, err
+	}
+	return NewOption(OptionDomainSearch, data), nil
+}
+
+// GetDomainSearch returns the decoded option 119
+func (o *Options) GetDomainSearch() ([]string, bool) {
+	data, ok := o.GetBytes(OptionDomainSearch)
+	if !ok {
+		return nil, false
+	}
+	domains, err := ParseDomainSearch(data)
+	if err != nil {
+		return nil, false
+	}
+	return domains, true
+}
@@ -0,0 +1,137 @@
+package pkt
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// OptionSpace describes an encapsulated option space: sub-options carried
+// as code/length/data triples inside the data of a top level option
+type OptionSpace struct {
+	Name string
+
+	// Framed spaces may contain Pad and End like the top level options,
+	// as vendor specific information does (RFC 2132 section 8.4)
+	Framed bool
+
+	// SubOptions holds the names of known sub-options
+	SubOptions map[OptionCode]string
+}
+
+var optionSpaces = map[OptionCode]OptionSpace{}
+
+// RegisterOptionSpace declares that the data of option code holds sub-options
+func RegisterOptionSpace(code OptionCode, space OptionSpace) {
+	optionSpaces[code] = space
+}
+
+// LookupOptionSpace returns the option space encapsulated in option code
+func LookupOptionSpace(code OptionCode) (OptionSpace, bool) {
+	space, ok := optionSpaces[code]
+	return space, ok
+}
+
+// VendorSpace is the vendor specific information space (option 43).
+// Sub-option codes are defined by each vendor.
+var VendorSpace = OptionSpace{
+	Name:   "Vendor Specific Information",
+	Framed: true,
+}
+
+func init() {
+	RegisterOptionSpace(OptionVendorSpecific, VendorSpace)
+}
+
+// SubOptionName returns the name of a sub-option
+func (sp OptionSpace) SubOptionName(code OptionCode) string {
+	if name, ok := sp.SubOptions[code]; ok {
+		return name
+	}
+	return fmt.Sprintf("Sub-option %d", uint8(code))
+}
+
+// Decode parses the sub-options in data
+func (sp OptionSpace) Decode(data []byte) (Options, error) {
+	var subs Options
+	if sp.Framed {
+		err := subs.decode(bytes.Clone(data), 0)
+		if err != nil {
+			return Options{}, err
+		}
+		subs.Delete(OptionEnd)
+		return subs, nil
+	}
+	for i := 0; i < len(data); {
+		if i+1 >= len(data) {
+			return Options{}, parseErr(i, ErrTruncated)
+		}
+		n := int(data[i+1])
+		if i+2+n > len(data) {
+			return Options{}, parseErr(i+1, ErrBadOptionLength)
+		}
+		subs.Add(Option{
+			Type: OptionCode(data[i]),
+			Data: append([]byte(nil), data[i+2:i+2+n]...),
+		})
+		i += 2 + n
+	}
+	return subs, nil
+}
+
+// Encode encodes sub-options as the data of the encapsulating option
+func (sp OptionSpace) Encode(subs Options) ([]byte, error) {
+	var buf []byte
+	for _, sub := range subs.Options {
+		if sp.Framed && (sub.Type == OptionPad || sub.Type == OptionEnd) {
+			continue
+		}
+		if len(sub.Data) > maxOptionLen {
+			return nil, fmt.Errorf("%w: %s %v", ErrBadOptionLength, sp.Name, sp.SubOptionName(sub.Type))
+		}
+		buf = append(buf, byte(sub.Type), byte(len(sub.Data)))
+		buf = append(buf, sub.Data...)
+	}
+	return buf, nil
+}
+
+// GetEncapsulated returns the decoded sub-options of an option with a
+// registered option space
+func (o *Options) GetEncapsulated(code OptionCode) (Options, bool) {
+	space, ok := LookupOptionSpace(code)
+	if !ok {
+		return Options{}, false
+	}
+	data, ok := o.GetBytes(code)
+	if !ok {
+		return Options{}, false
+	}
+	subs, err := space.Decode(data)
+	if err != nil {
+		return Options{}, false
+	}
+	return subs, true
+}
+
+// NewOptionEncapsulated creates an option holding sub-options in the
+// option space registered for code
+func NewOptionEncapsulated(code OptionCode, subs Options) (Option, error) {
+	space, ok := LookupOptionSpace(code)
+	if !ok {
+		return Option{}, fmt.Errorf("%v does not encapsulate sub-options", code)
+	}
+	data, err := space.Encode(subs)
+	if err != nil {
+		return Option{}, err
+	}
+	return NewOption(code, data), nil
+}
+
+// NewOptionVendorSpecific creates option 43 from vendor sub-options
+func NewOptionVendorSpecific(subs Options) (Option, error) {
+	return NewOptionEncapsulated(OptionVendorSpecific, subs)
+}
+
+// VendorClass returns the vendor class identifier (option 60) of the packet
+func (p *Pkt) VendorClass() (string, bool) {
+	return p.Options.GetString(OptionVendorClassIdentifier)
+}
@@ -0,0 +1,31 @@
+package pkt
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrTruncated       = errors.New("packet truncated")
+	ErrBadCookie       = errors.New("bad magic cookie")
+	ErrBadOptionLength = errors.New("bad option length")
+)
+
+// ParseError reports where in a packet decoding failed.
+// It wraps one of the Err* values so it can be checked with errors.Is.
+type ParseError struct {
+	Offset int
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%v at offset %d", e.Err, e.Offset)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+func parseErr(offset int, err error) error {
+	return &ParseError{Offset: offset, Err: err}
+}
@@ -0,0 +1,298 @@
+package pkt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+)
+
+var hardwareTypeNames = map[uint8]string{
+	HTypeEthernet:   "Ethernet",
+	HTypeIEEE802:    "IEEE 802",
+	HTypeInfiniBand: "InfiniBand",
+}
+
+// HardwareTypeName returns the name of a hardware type
+func HardwareTypeName(htype uint8) string {
+	if name, ok := hardwareTypeNames[htype]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+func opCodeName(op uint8) string {
+	switch op {
+	case OpCodeBootRequest:
+		return "Boot Request"
+	case OpCodeBootReply:
+		return "Boot Reply"
+	}
+	return "Unknown"
+}
+
+// cString returns a NUL terminated header field as a string
+func cString(b []byte) string {
+	if i := strings.IndexByte(string(b), 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+// Format returns a Wireshark style dissection of the packet: one line per
+// header field and a subtree per option, with decoded names and values
+func (p *Pkt) Format() string {
+	var b strings.Builder
+	h := &p.Header
+	line := func(depth int, format string, args ...any) {
+		b.WriteString(strings.Repeat("    ", depth))
+		fmt.Fprintf(&b, format, args...)
+		b.WriteString("\n")
+	}
+
+	title := "Dynamic Host Configuration Protocol"
+	if t := p.MessageType(); t != 0 {
+		title += fmt.Sprintf(" (%v)", t)
+	} else if p.IsBOOTP() {
+		title = fmt.Sprintf("Bootstrap Protocol (%s)", opCodeName(h.OpCode))
+	}
+	line(0, "%s", title)
+	line(1, "Message type: %s (%d)", opCodeName(h.OpCode), h.OpCode)
+	line(1, "Hardware type: %s (0x%02x)", HardwareTypeName(h.HType), h.HType)
+	line(1, "Hardware address length: %d", h.HLen)
+	line(1, "Hops: %d", h.Hops)
+	line(1, "Transaction ID: 0x%08x", h.XID)
+	line(1, "Seconds elapsed: %d", h.Secs)
+	flags := "Unicast"
+	if h.Flags&FlagBroadcast != 0 {
+		flags = "Broadcast"
+	}
+	line(1, "Bootp flags: 0x%04x (%s)", h.Flags, flags)
+	line(1, "Client IP address: %v", net.IP(h.CIAddr[:]))
+	line(1, "Your (client) IP address: %v", net.IP(h.YIAddr[:]))
+	line(1, "Next server IP address: %v", net.IP(h.SIAddr[:]))
+	line(1, "Relay agent IP address: %v", net.IP(h.GIAddr[:]))
+	if h.HLen > 0 {
+		line(1, "Client hardware address: %s", p.PrintMAC())
+	} else {
+		line(1, "Client hardware address not given")
+	}
+	if sname := cString(h.SName[:]); sname != "" {
+		line(1, "Server host name: %q", sname)
+	} else {
+		line(1, "Server host name not given")
+	}
+	if file := cString(h.File[:]); file != "" {
+		line(1, "Boot file name: %q", file)
+	} else {
+		line(1, "Boot file name not given")
+	}
+	if p.Vendor != nil {
+		line(1, "Vendor area: %x", p.Vendor)
+	} else {
+		line(1, "Magic cookie: %x", h.Cookie[:])
+	}
+
+	for _, opt := range p.Options.Options {
+		line(1, "Option: (%d) %v", opt.Type, opt.Type)
+		if opt.Type == OptionEnd || opt.Type == OptionPad {
+			continue
+		}
+		line(2, "Length: %d", opt.Len())
+		space, ok := LookupOptionSpace(opt.Type)
+		if !ok {
+			line(2, "Value: %s", FormatOption(opt))
+			continue
+		}
+		subs, err := space.Decode(opt.Data)
+		if err != nil {
+			line(2, "Value: %x (malformed: %v)", opt.Data, err)
+			continue
+		}
+		for _, sub := range subs.Options {
+			line(2, "Sub-option: (%d) %s", sub.Type, space.SubOptionName(sub.Type))
+			line(3, "Length: %d", sub.Len())
+			line(3, "Value: %s", printableOrHex(sub.Data))
+		}
+	}
+	return b.String()
+}
+
+// FormatOption returns the decoded value of an option as text, using the
+// option kind from the registry. Unknown or malformed options are shown as hex.
+func FormatOption(opt Option) string {
+	if space, ok := LookupOptionSpace(opt.Type); ok {
+		subs, err := space.Decode(opt.Data)
+		if err != nil {
+			return fmt.Sprintf("%x (malformed)", opt.Data)
+		}
+		var vs []string
+		for _, sub := range subs.Options {
+			vs = append(vs, space.SubOptionName(sub.Type)+"="+printableOrHex(sub.Data))
+		}
+		return strings.Join(vs, ", ")
+	}
+	info, ok := LookupOption(opt.Type)
+	if !ok {
+		return fmt.Sprintf("%x", opt.Data)
+	}
+	s, ok := formatKind(info.Kind, opt)
+	if !ok {
+		return fmt.Sprintf("%x (malformed)", opt.Data)
+	}
+	return s
+}
+
+func formatKind(kind OptionKind, opt Option) (string, bool) {
+	data := opt.Data
+	switch kind {
+	case KindNone:
+		return "", len(data) == 0
+	case KindIP:
+		if len(data) != net.IPv4len {
+			return "", false
+		}
+		return net.IP(data).String(), true
+	case KindIPList, KindIPPairs:
+		if len(data) == 0 || len(data)%net.IPv4len != 0 {
+			return "", false
+		}
+		var ips []string
+		for i := 0; i < len(data); i += net.IPv4len {
+			ips = append(ips, net.IP(data[i:i+net.IPv4len]).String())
+		}
+		if kind == KindIPList {
+			return strings.Join(ips, ", "), true
+		}
+		if len(ips)%2 != 0 {
+			return "", false
+		}
+		var pairs []string
+		for i := 0; i < len(ips); i += 2 {
+			pairs = append(pairs, ips[i]+" "+ips[i+1])
+		}
+		return strings.Join(pairs, ", "), true
+	case KindUint8:
+		if len(data) != 1 {
+			return "", false
+		}
+		switch opt.Type {
+		case OptionDHCPMessageType:
+			return fmt.Sprintf("%v (%d)", MessageType(data[0]), data[0]), true
+		case OptionOverload:
+			return fmt.Sprintf("%s (%d)", overloadName(data[0]), data[0]), true
+		}
+		return fmt.Sprintf("%d", data[0]), true
+	case KindUint16:
+		if len(data) != 2 {
+			return "", false
+		}
+		return fmt.Sprintf("%d", binary.BigEndian.Uint16(data)), true
+	case KindUint16List:
+		if len(data) == 0 || len(data)%2 != 0 {
+			return "", false
+		}
+		var vs []string
+		for i := 0; i < len(data); i += 2 {
+			vs = append(vs, fmt.Sprintf("%d", binary.BigEndian.Uint16(data[i:])))
+		}
+		return strings.Join(vs, ", "), true
+	case KindInt32:
+		if len(data) != 4 {
+			return "", false
+		}
+		return fmt.Sprintf("%ds", int32(binary.BigEndian.Uint32(data))), true
+	case KindDuration:
+		if len(data) != 4 {
+			return "", false
+		}
+		secs := binary.BigEndian.Uint32(data)
+		if secs == 0xffffffff {
+			return "infinity", true
+		}
+		return fmt.Sprintf("%d (%v)", secs, time.Duration(secs)*time.Second), true
+	case KindBool:
+		if len(data) != 1 || data[0] > 1 {
+			return "", false
+		}
+		return fmt.Sprintf("%t", data[0] == 1), true
+	case KindString:
+		return fmt.Sprintf("%q", data), true
+	case KindCodeList:
+		var codes []string
+		for _, c := range data {
+			codes = append(codes, fmt.Sprintf("(%d) %v", c, OptionCode(c)))
+		}
+		return strings.Join(codes, ", "), true
+	case KindDomainList:
+		domains, err := ParseDomainSearch(data)
+		if err != nil {
+			return "", false
+		}
+		return strings.Join(domains, ", "), true
+	case KindClasslessRoutes:
+		routes, err := ParseClasslessRoutes(data)
+		if err != nil {
+			return "", false
+		}
+		var rs []string
+		for _, r := range routes {
+			rs = append(rs, r.String())
+		}
+		return strings.Join(rs, ", "), true
+	}
+	return fmt.Sprintf("%x", data), true
+}
+
+func overloadName(v uint8) string {
+	switch v {
+	case OverloadFile:
+		return "file"
+	case OverloadSName:
+		return "sname"
+	case OverloadBoth:
+		return "file and sname"
+	}
+	return "unknown"
+}
+
+// LogValue implements slog.LogValuer so packets are logged as structured
+// attributes instead of raw byte arrays
+func (p *Pkt) LogValue() slog.Value {
+	h := &p.Header
+	typ := p.MessageType().String()
+	if p.IsBOOTP() {
+		typ = "BOOTP"
+	}
+	attrs := []slog.Attr{
+		slog.String("op", opCodeName(h.OpCode)),
+		slog.String("type", typ),
+		slog.String("xid", fmt.Sprintf("0x%08x", h.XID)),
+		slog.String("chaddr", p.PrintMAC()),
+		slog.Int("htype", int(h.HType)),
+		slog.Int("hops", int(h.Hops)),
+		slog.Int("secs", int(h.Secs)),
+		slog.String("flags", fmt.Sprintf("0x%04x", h.Flags)),
+		slog.String("ciaddr", net.IP(h.CIAddr[:]).String()),
+		slog.String("yiaddr", net.IP(h.YIAddr[:]).String()),
+		slog.String("siaddr", net.IP(h.SIAddr[:]).String()),
+		slog.String("giaddr", net.IP(h.GIAddr[:]).String()),
+	}
+	var opts []any
+	for _, opt := range p.Options.Options {
+		if opt.Type == OptionEnd || opt.Type == OptionPad {
+			continue
+		}
+		opts = append(opts, slog.String(opt.Type.String(), FormatOption(opt)))
+	}
+	attrs = append(attrs, slog.Group("options", opts...))
+	return slog.GroupValue(attrs...)
+}
+
+// String returns the packet as a single line of attributes, for log
+// handlers that do not resolve slog.LogValuer
+func (p *Pkt) String() string {
+	return p.LogValue().String()
+}
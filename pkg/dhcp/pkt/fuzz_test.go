@@ -0,0 +1,119 @@
+package pkt
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// addCorpus seeds f with the packets in testdata
+func addCorpus(f *testing.F, seed func(b []byte)) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.bin"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	if len(files) == 0 {
+		f.Fatal("no packets in testdata")
+	}
+	for _, name := range files {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			f.Fatal(err)
+		}
+		seed(b)
+	}
+}
+
+func FuzzUnmarshalBinary(f *testing.F) {
+	addCorpus(f, func(b []byte) { f.Add(b) })
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var p Pkt
+		if err := p.UnmarshalBinary(b); err != nil {
+			return
+		}
+
+		// everything that reads a decoded packet must cope with any input
+		_ = p.Validate()
+		_ = p.Format()
+		_ = p.String()
+		checkJSONRoundTrip(t, &p)
+
+		// decode, encode, decode must be stable
+		enc, err := p.MarshalBinary()
+		if err != nil {
+			t.Fatalf("failed to encode decoded packet: %v", err)
+		}
+		var q Pkt
+		if err := q.UnmarshalBinary(enc); err != nil {
+			t.Fatalf("failed to decode encoded packet: %v\n% x", err, enc)
+		}
+		if q.Header != p.Header {
+			t.Fatalf("header changed:\n%+v\n%+v", p.Header, q.Header)
+		}
+		enc2, err := q.MarshalBinary()
+		if err != nil {
+			t.Fatalf("failed to encode again: %v", err)
+		}
+		if !bytes.Equal(enc, enc2) {
+			t.Fatalf("encoding not stable:\n% x\n% x", enc, enc2)
+		}
+
+		// the view sees the same options as the full decode
+		v, err := NewView(b)
+		if err != nil {
+			t.Fatalf("view rejected a decodable packet: %v", err)
+		}
+		it := v.Options()
+		for it.Next() {
+			if !p.Options.Has(it.Code()) {
+				t.Fatalf("option %d seen by the view only", it.Code())
+			}
+		}
+		if it.Err() != nil {
+			t.Fatalf("view failed on a decodable packet: %v", it.Err())
+		}
+	})
+}
+
+func FuzzOptionsDecode(f *testing.F) {
+	addCorpus(f, func(b []byte) {
+		if len(b) > headerLen {
+			f.Add(b[headerLen:])
+		}
+	})
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var o Options
+		if err := o.Decode(bytes.NewReader(b)); err != nil {
+			return
+		}
+		for _, opt := range o.Options {
+			_ = FormatOption(opt)
+		}
+
+		enc, err := o.MarshalBinary()
+		if err != nil {
+			t.Fatalf("failed to encode decoded options: %v", err)
+		}
+		var o2 Options
+		if err := o2.Decode(bytes.NewReader(enc)); err != nil {
+			t.Fatalf("failed to decode encoded options: %v\n% x", err, enc)
+		}
+		if len(o2.Options) != len(o.Options) {
+			t.Fatalf("got %d options, want %d", len(o2.Options), len(o.Options))
+		}
+		for i := range o.Options {
+			a, b := o.Options[i], o2.Options[i]
+			if a.Type != b.Type || !bytes.Equal(a.Data, b.Data) {
+				t.Fatalf("option %d changed: %v != %v", i, a, b)
+			}
+		}
+		enc2, err := o2.MarshalBinary()
+		if err != nil {
+			t.Fatalf("failed to encode again: %v", err)
+		}
+		if !bytes.Equal(enc, enc2) {
+			t.Fatalf("encoding not stable:\n% x\n% x", enc, enc2)
+		}
+	})
+}
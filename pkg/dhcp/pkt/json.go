@@ -0,0 +1,513 @@
+package pkt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// jsonPkt is the JSON and YAML representation of a packet
+type jsonPkt struct {
+	Op      uint8        `json:"op" yaml:"op"`
+	HType   uint8        `json:"htype" yaml:"htype"`
+	HLen    uint8        `json:"hlen" yaml:"hlen"`
+	Hops    uint8        `json:"hops" yaml:"hops"`
+	XID     string       `json:"xid" yaml:"xid"`
+	Secs    uint16       `json:"secs" yaml:"secs"`
+	Flags   uint16       `json:"flags" yaml:"flags"`
+	CIAddr  string       `json:"ciaddr" yaml:"ciaddr"`
+	YIAddr  string       `json:"yiaddr" yaml:"yiaddr"`
+	SIAddr  string       `json:"siaddr" yaml:"siaddr"`
+	GIAddr  string       `json:"giaddr" yaml:"giaddr"`
+	CHAddr  string       `json:"chaddr" yaml:"chaddr"`
+	CHPad   string       `json:"chaddr_pad,omitempty" yaml:"chaddr_pad,omitempty"`
+	SName   string       `json:"sname,omitempty" yaml:"sname,omitempty"`
+	SNameX  string       `json:"sname_hex,omitempty" yaml:"sname_hex,omitempty"`
+	File    string       `json:"file,omitempty" yaml:"file,omitempty"`
+	FileX   string       `json:"file_hex,omitempty" yaml:"file_hex,omitempty"`
+	Cookie  string       `json:"cookie,omitempty" yaml:"cookie,omitempty"`
+	Vendor  string       `json:"vendor,omitempty" yaml:"vendor,omitempty"`
+	Options []jsonOption `json:"options" yaml:"options"`
+}
+
+// jsonOption holds either a typed value or, for unknown codes and data
+// that does not decode, the raw data as hex
+type jsonOption struct {
+	Code  uint8  `json:"code" yaml:"code"`
+	Name  string `json:"name,omitempty" yaml:"name,omitempty"`
+	Value any    `json:"value,omitempty" yaml:"value,omitempty"`
+	Hex   string `json:"hex,omitempty" yaml:"hex,omitempty"`
+}
+
+func (p *Pkt) toJSON() jsonPkt {
+	h := &p.Header
+	j := jsonPkt{
+		Op:      h.OpCode,
+		HType:   h.HType,
+		HLen:    h.HLen,
+		Hops:    h.Hops,
+		XID:     fmt.Sprintf("0x%08x", h.XID),
+		Secs:    h.Secs,
+		Flags:   h.Flags,
+		CIAddr:  net.IP(h.CIAddr[:]).String(),
+		YIAddr:  net.IP(h.YIAddr[:]).String(),
+		SIAddr:  net.IP(h.SIAddr[:]).String(),
+		GIAddr:  net.IP(h.GIAddr[:]).String(),
+		CHAddr:  p.PrintMAC(),
+		Cookie:  hex.EncodeToString(h.Cookie[:]),
+		Options: []jsonOption{},
+	}
+	// the bytes after hlen are normally zero, but keep them if not
+	if pad := h.CHAddr[min(int(h.HLen), len(h.CHAddr)):]; !isZero(pad) {
+		j.CHPad = hex.EncodeToString(pad)
+	}
+	j.SName, j.SNameX = textField(h.SName[:])
+	j.File, j.FileX = textField(h.File[:])
+	if p.Vendor != nil {
+		j.Cookie = ""
+		j.Vendor = hex.EncodeToString(p.Vendor)
+	}
+	for _, opt := range p.Options.Options {
+		if opt.Type == OptionPad || opt.Type == OptionEnd {
+			continue
+		}
+		j.Options = append(j.Options, optionToJSON(opt))
+	}
+	return j
+}
+
+// textField returns sname or file as text, or as hex if the text would
+// not give back the same bytes: data after the terminating zero, or data
+// that is not UTF-8
+func textField(b []byte) (string, string) {
+	s := cString(b)
+	if utf8.ValidString(s) && isZero(b[len(s):]) {
+		return s, ""
+	}
+	return "", hex.EncodeToString(b)
+}
+
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func optionToJSON(opt Option) jsonOption {
+	j := jsonOption{Code: uint8(opt.Type)}
+	info, ok := LookupOption(opt.Type)
+	if ok {
+		j.Name = info.Name
+		if v, ok := jsonValue(info.Kind, opt); ok {
+			j.Value = v
+			return j
+		}
+	}
+	j.Hex = hex.EncodeToString(opt.Data)
+	return j
+}
+
+// jsonValue decodes option data into a JSON friendly value
+func jsonValue(kind OptionKind, opt Option) (any, bool) {
+	data := opt.Data
+	switch kind {
+	case KindIP:
+		if len(data) != net.IPv4len {
+			return nil, false
+		}
+		return net.IP(data).String(), true
+	case KindIPList, KindIPPairs:
+		if len(data) == 0 || len(data)%net.IPv4len != 0 {
+			return nil, false
+		}
+		var ips []string
+		for i := 0; i < len(data); i += net.IPv4len {
+			ips = append(ips, net.IP(data[i:i+net.IPv4len]).String())
+		}
+		return ips, true
+	case KindUint8:
+		if len(data) != 1 {
+			return nil, false
+		}
+		if opt.Type == OptionDHCPMessageType && MessageType(data[0]).Valid() {
+			return MessageType(data[0]).String(), true
+		}
+		return data[0], true
+	case KindUint16:
+		if len(data) != 2 {
+			return nil, false
+		}
+		return binary.BigEndian.Uint16(data), true
+	case KindUint16List:
+		if len(data) == 0 || len(data)%2 != 0 {
+			return nil, false
+		}
+		var vs []uint16
+		for i := 0; i < len(data); i += 2 {
+			vs = append(vs, binary.BigEndian.Uint16(data[i:]))
+		}
+		return vs, true
+	case KindInt32:
+		if len(data) != 4 {
+			return nil, false
+		}
+		return int32(binary.BigEndian.Uint32(data)), true
+	case KindDuration:
+		if len(data) != 4 {
+			return nil, false
+		}
+		return binary.BigEndian.Uint32(data), true
+	case KindBool:
+		if len(data) != 1 || data[0] > 1 {
+			return nil, false
+		}
+		return data[0] == 1, true
+	case KindString:
+		// JSON strings are UTF-8, anything else is kept as hex
+		if !utf8.Valid(data) {
+			return nil, false
+		}
+		return string(data), true
+	case KindCodeList:
+		// not []uint8, which encoding/json writes as base64
+		codes := make([]int, len(data))
+		for i := range data {
+			codes[i] = int(data[i])
+		}
+		return codes, true
+	case KindDomainList:
+		domains, err := ParseDomainSearch(data)
+		if err != nil {
+			return nil, false
+		}
+		// compression other than ours would not survive the round trip
+		if enc, err := EncodeDomainSearch(domains); err != nil || !bytes.Equal(enc, data) {
+			return nil, false
+		}
+		return domains, true
+	case KindClasslessRoutes:
+		routes, err := ParseClasslessRoutes(data)
+		if err != nil {
+			return nil, false
+		}
+		if enc, err := EncodeClasslessRoutes(routes); err != nil || !bytes.Equal(enc, data) {
+			return nil, false
+		}
+		var rs []string
+		for _, r := range routes {
+			rs = append(rs, r.String())
+		}
+		return rs, true
+	}
+	return nil, false
+}
+
+func (p *Pkt) fromJSON(j jsonPkt) error {
+	var h Header
+	var err error
+	h.OpCode = j.Op
+	h.HType = j.HType
+	h.HLen = j.HLen
+	h.Hops = j.Hops
+	xid, err := strconv.ParseUint(j.XID, 0, 32)
+	if err != nil {
+		return fmt.Errorf("invalid xid %q: %w", j.XID, err)
+	}
+	h.XID = uint32(xid)
+	h.Secs = j.Secs
+	h.Flags = j.Flags
+	for _, f := range []struct {
+		dst  *[4]byte
+		name string
+		s    string
+	}{
+		{&h.CIAddr, "ciaddr", j.CIAddr},
+		{&h.YIAddr, "yiaddr", j.YIAddr},
+		{&h.SIAddr, "siaddr", j.SIAddr},
+		{&h.GIAddr, "giaddr", j.GIAddr},
+	} {
+		*f.dst, err = parseIPv4(f.s)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", f.name, err)
+		}
+	}
+	chaddr, err := parseColonHex(j.CHAddr)
+	if err != nil || len(chaddr) > len(h.CHAddr) {
+		return fmt.Errorf("invalid chaddr %q", j.CHAddr)
+	}
+	pad, err := hex.DecodeString(j.CHPad)
+	if err != nil || len(chaddr)+len(pad) > len(h.CHAddr) {
+		return fmt.Errorf("invalid chaddr_pad %q", j.CHPad)
+	}
+	copy(h.CHAddr[copy(h.CHAddr[:], chaddr):], pad)
+	err = parseTextField(h.SName[:], "sname", j.SName, j.SNameX)
+	if err != nil {
+		return err
+	}
+	err = parseTextField(h.File[:], "file", j.File, j.FileX)
+	if err != nil {
+		return err
+	}
+	if j.Vendor != "" {
+		vendor, err := hex.DecodeString(j.Vendor)
+		if err != nil {
+			return fmt.Errorf("invalid vendor area %q", j.Vendor)
+		}
+		p.Header = h
+		p.Options = Options{}
+		p.Vendor = vendor
+		return nil
+	}
+	cookie, err := hex.DecodeString(j.Cookie)
+	if err != nil || len(cookie) != len(h.Cookie) {
+		return fmt.Errorf("invalid cookie %q", j.Cookie)
+	}
+	copy(h.Cookie[:], cookie)
+
+	var opts Options
+	for _, jo := range j.Options {
+		opt, err := optionFromJSON(jo)
+		if err != nil {
+			return err
+		}
+		opts.Add(opt)
+	}
+	opts.Add(NewOptionEnd())
+
+	p.Header = h
+	p.Options = opts
+	p.Vendor = nil
+	return nil
+}
+
+// parseTextField fills dst, the sname or file field, from its text or
+// hex form
+func parseTextField(dst []byte, name, text, hexText string) error {
+	data := []byte(text)
+	if hexText != "" {
+		var err error
+		data, err = hex.DecodeString(hexText)
+		if err != nil {
+			return fmt.Errorf("invalid %s_hex %q", name, hexText)
+		}
+	}
+	if len(data) > len(dst) {
+		return fmt.Errorf("%s too long", name)
+	}
+	copy(dst, data)
+	return nil
+}
+
+func optionFromJSON(j jsonOption) (Option, error) {
+	code := OptionCode(j.Code)
+	if j.Hex != "" || j.Value == nil {
+		data, err := hex.DecodeString(j.Hex)
+		if err != nil {
+			return Option{}, fmt.Errorf("invalid hex for %v: %w", code, err)
+		}
+		return NewOption(code, data), nil
+	}
+	info, ok := LookupOption(code)
+	if !ok {
+		return Option{}, fmt.Errorf("%v has a value but no known type, use hex", code)
+	}
+	data, err := jsonData(info.Kind, code, j.Value)
+	if err != nil {
+		return Option{}, fmt.Errorf("invalid value for %v: %w", code, err)
+	}
+	return NewOption(code, data), nil
+}
+
+// jsonData encodes a decoded JSON or YAML value back into option data
+func jsonData(kind OptionKind, code OptionCode, v any) ([]byte, error) {
+	switch kind {
+	case KindIP:
+		s, _ := v.(string)
+		ip, err := parseIPv4(s)
+		return ip[:], err
+	case KindIPList, KindIPPairs:
+		var data []byte
+		for _, item := range toList(v) {
+			s, _ := item.(string)
+			ip, err := parseIPv4(s)
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, ip[:]...)
+		}
+		return data, nil
+	case KindUint8:
+		if s, ok := v.(string); ok && code == OptionDHCPMessageType {
+			for t, name := range messageTypeNames {
+				if name == s {
+					return []byte{byte(t)}, nil
+				}
+			}
+			return nil, fmt.Errorf("unknown message type %q", s)
+		}
+		n, err := toUint(v, math.MaxUint8)
+		return []byte{byte(n)}, err
+	case KindUint16:
+		n, err := toUint(v, math.MaxUint16)
+		return binary.BigEndian.AppendUint16(nil, uint16(n)), err
+	case KindUint16List:
+		var data []byte
+		for _, item := range toList(v) {
+			n, err := toUint(item, math.MaxUint16)
+			if err != nil {
+				return nil, err
+			}
+			data = binary.BigEndian.AppendUint16(data, uint16(n))
+		}
+		return data, nil
+	case KindInt32:
+		f, ok := toFloat(v)
+		if !ok || f < math.MinInt32 || f > math.MaxInt32 {
+			return nil, fmt.Errorf("not a 32 bit integer: %v", v)
+		}
+		return binary.BigEndian.AppendUint32(nil, uint32(int32(f))), nil
+	case KindDuration:
+		n, err := toUint(v, math.MaxUint32)
+		return binary.BigEndian.AppendUint32(nil, uint32(n)), err
+	case KindBool:
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("not a boolean: %v", v)
+		}
+		if b {
+			return []byte{1}, nil
+		}
+		return []byte{0}, nil
+	case KindString:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("not a string: %v", v)
+		}
+		return []byte(s), nil
+	case KindCodeList:
+		var data []byte
+		for _, item := range toList(v) {
+			n, err := toUint(item, math.MaxUint8)
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, byte(n))
+		}
+		return data, nil
+	case KindDomainList:
+		var domains []string
+		for _, item := range toList(v) {
+			s, _ := item.(string)
+			domains = append(domains, s)
+		}
+		return EncodeDomainSearch(domains)
+	case KindClasslessRoutes:
+		var routes []Route
+		for _, item := range toList(v) {
+			s, _ := item.(string)
+			dest, router, ok := strings.Cut(s, " via ")
+			_, ipnet, err := net.ParseCIDR(dest)
+			if !ok || err != nil || net.ParseIP(router) == nil {
+				return nil, fmt.Errorf("invalid route %q", s)
+			}
+			routes = append(routes, Route{Dest: *ipnet, Router: net.ParseIP(router)})
+		}
+		return EncodeClasslessRoutes(routes)
+	}
+	return nil, fmt.Errorf("values of this type must be given as hex")
+}
+
+func toList(v any) []any {
+	list, _ := v.([]any)
+	return list
+}
+
+// toFloat converts the number types produced by JSON and YAML decoders
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func toUint(v any, max uint64) (uint64, error) {
+	f, ok := toFloat(v)
+	if !ok || f < 0 || f > float64(max) || f != math.Trunc(f) {
+		return 0, fmt.Errorf("not an integer between 0 and %d: %v", max, v)
+	}
+	return uint64(f), nil
+}
+
+func parseIPv4(s string) ([4]byte, error) {
+	ip := net.ParseIP(s).To4()
+	if ip == nil {
+		return [4]byte{}, fmt.Errorf("%q is not an IPv4 address", s)
+	}
+	return [4]byte(ip), nil
+}
+
+// parseColonHex parses a hardware address of any length, as written by PrintMAC
+func parseColonHex(s string) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var b []byte
+	for _, part := range strings.Split(s, ":") {
+		v, err := strconv.ParseUint(part, 16, 8)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, byte(v))
+	}
+	return b, nil
+}
+
+func (p *Pkt) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.toJSON())
+}
+
+func (p *Pkt) UnmarshalJSON(b []byte) error {
+	var j jsonPkt
+	err := json.Unmarshal(b, &j)
+	if err != nil {
+		return err
+	}
+	return p.fromJSON(j)
+}
+
+// MarshalYAML implements the marshaler interface of the common YAML
+// packages, using the same representation as MarshalJSON
+func (p *Pkt) MarshalYAML() (any, error) {
+	return p.toJSON(), nil
+}
+
+// UnmarshalYAML implements the unmarshaler interface of the common YAML
+// packages, using the same representation as UnmarshalJSON
+func (p *Pkt) UnmarshalYAML(unmarshal func(any) error) error {
+	var j jsonPkt
+	err := unmarshal(&j)
+	if err != nil {
+		return err
+	}
+	return p.fromJSON(j)
+}
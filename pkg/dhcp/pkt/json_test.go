@@ -0,0 +1,80 @@
+package pkt
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// checkJSONRoundTrip checks that p survives encoding to JSON and back
+func checkJSONRoundTrip(t *testing.T, p *Pkt) {
+	t.Helper()
+	b, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("failed to encode JSON: %v", err)
+	}
+	var q Pkt
+	if err := json.Unmarshal(b, &q); err != nil {
+		t.Fatalf("failed to decode JSON: %v\n%s", err, b)
+	}
+	if q.Header != p.Header {
+		t.Fatalf("header changed:\n%+v\n%+v\n%s", p.Header, q.Header, b)
+	}
+	if !bytes.Equal(q.Vendor, p.Vendor) {
+		t.Fatalf("vendor area changed:\n% x\n% x", p.Vendor, q.Vendor)
+	}
+	want, got := dataOptions(p), dataOptions(&q)
+	if len(got) != len(want) {
+		t.Fatalf("got %d options, want %d\n%s", len(got), len(want), b)
+	}
+	for i := range want {
+		if got[i].Type != want[i].Type || !bytes.Equal(got[i].Data, want[i].Data) {
+			t.Fatalf("option %d changed: %v != %v\n%s", i, want[i], got[i], b)
+		}
+	}
+}
+
+// dataOptions returns the options of p without Pad and End, which the
+// JSON form leaves out
+func dataOptions(p *Pkt) []Option {
+	var opts []Option
+	for _, opt := range p.Options.Options {
+		if opt.Type != OptionPad && opt.Type != OptionEnd {
+			opts = append(opts, opt)
+		}
+	}
+	return opts
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range files {
+		t.Run(filepath.Base(name), func(t *testing.T) {
+			b, err := os.ReadFile(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var p Pkt
+			if err := p.UnmarshalBinary(b); err != nil {
+				t.Fatalf("failed to decode: %v", err)
+			}
+			checkJSONRoundTrip(t, &p)
+		})
+	}
+}
+
+func TestJSONKeepsRawBytes(t *testing.T) {
+	p := overloadOffer()
+	p.Header.CHAddr[10] = 0xee
+	copy(p.Header.SName[:], "tftp\x00junk")
+	p.Header.File[0] = 0xff
+	p.Options.Add(NewOption(OptionHostName, []byte{'p', 'l', 'c', 0xff, 0xfe}))
+	p.Options.Add(NewOption(OptionDomainSearch, []byte{3, 'c', 'o', 'm', 0, 0xc0, 0}))
+	p.Options.Add(NewOptionEnd())
+	checkJSONRoundTrip(t, p)
+}
@@ -0,0 +1,88 @@
+package pkt
+
+import "fmt"
+
+// MessageType is the value of the DHCP message type option (53)
+type MessageType uint8
+
+const (
+	// RFC 2131
+	MessageTypeDiscover MessageType = 1
+	MessageTypeOffer    MessageType = 2
+	MessageTypeRequest  MessageType = 3
+	MessageTypeDecline  MessageType = 4
+	MessageTypeAck      MessageType = 5
+	MessageTypeNak      MessageType = 6
+	MessageTypeRelease  MessageType = 7
+	MessageTypeInform   MessageType = 8
+
+	// RFC 3203
+	MessageTypeForceRenew MessageType = 9
+
+	// RFC 4388
+	MessageTypeLeaseQuery      MessageType = 10
+	MessageTypeLeaseUnassigned MessageType = 11
+	MessageTypeLeaseUnknown    MessageType = 12
+	MessageTypeLeaseActive     MessageType = 13
+)
+
+var messageTypeNames = map[MessageType]string{
+	MessageTypeDiscover:        "DHCPDISCOVER",
+	MessageTypeOffer:           "DHCPOFFER",
+	MessageTypeRequest:         "DHCPREQUEST",
+	MessageTypeDecline:         "DHCPDECLINE",
+	MessageTypeAck:             "DHCPACK",
+	MessageTypeNak:             "DHCPNAK",
+	MessageTypeRelease:         "DHCPRELEASE",
+	MessageTypeInform:          "DHCPINFORM",
+	MessageTypeForceRenew:      "DHCPFORCERENEW",
+	MessageTypeLeaseQuery:      "DHCPLEASEQUERY",
+	MessageTypeLeaseUnassigned: "DHCPLEASEUNASSIGNED",
+	MessageTypeLeaseUnknown:    "DHCPLEASEUNKNOWN",
+	MessageTypeLeaseActive:     "DHCPLEASEACTIVE",
+}
+
+func (t MessageType) String() string {
+	if name, ok := messageTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("MessageType(%d)", uint8(t))
+}
+
+// Valid reports whether t is a known message type
+func (t MessageType) Valid() bool {
+	_, ok := messageTypeNames[t]
+	return ok
+}
+
+// IsClientMessage reports whether t is sent from a client to a server
+func (t MessageType) IsClientMessage() bool {
+	switch t {
+	case MessageTypeDiscover, MessageTypeRequest, MessageTypeDecline,
+		MessageTypeRelease, MessageTypeInform, MessageTypeLeaseQuery:
+		return true
+	}
+	return false
+}
+
+// MessageType returns the DHCP message type of the packet,
+// or 0 if the packet carries no message type option
+func (p *Pkt) MessageType() MessageType {
+	t, ok := p.Options.GetUint8(OptionDHCPMessageType)
+	if !ok {
+		return 0
+	}
+	return MessageType(t)
+}
+
+// Is reports whether the packet is a BOOTREQUEST of the given message type
+// when t is a client message, or a BOOTREPLY otherwise
+func (p *Pkt) Is(t MessageType) bool {
+	if p.MessageType() != t {
+		return false
+	}
+	if t.IsClientMessage() {
+		return p.Header.OpCode == OpCodeBootRequest
+	}
+	return p.Header.OpCode == OpCodeBootReply
+}
@@ -1 +1,49 @@
 package pkt
+
+import "net"
+
+// NewReplyTo returns a DHCPOFFER, DHCPACK or DHCPNAK answering req, with
+// the header fields RFC 2131 table 3 takes from the request: xid, flags,
+// giaddr, chaddr, htype and hlen, and ciaddr for an ACK. The reply holds
+// the message type option and accepts messages up to the size the client
+// allows. Callers set yiaddr and siaddr and add the remaining options.
+func NewReplyTo(req *Pkt, t MessageType) *Pkt {
+	p := &Pkt{
+		Header: Header{
+			OpCode: OpCodeBootReply,
+			HType:  req.Header.HType,
+			HLen:   req.Header.HLen,
+			XID:    req.Header.XID,
+			Flags:  req.Header.Flags,
+			GIAddr: req.Header.GIAddr,
+			CHAddr: req.Header.CHAddr,
+			Cookie: [4]byte(dhcpMagicCookie),
+		},
+		MaxSize: req.ClientMaxSize(),
+	}
+	switch t {
+	case MessageTypeAck:
+		p.Header.CIAddr = req.Header.CIAddr
+	case MessageTypeNak:
+		// a relay agent cannot know where the client is (RFC 2131 4.3.2)
+		if p.Header.GIAddr != [4]byte{} {
+			p.Header.Flags |= FlagBroadcast
+		}
+	}
+	p.Options.Add(NewOptionMessageType(t))
+	return p
+}
+
+// ReplyAddr returns where a server sends p, a reply to a client, as
+// described in RFC 2131 section 4.1: to the relay agent if there is one,
+// to a configured client by unicast, and broadcast otherwise
+func (p *Pkt) ReplyAddr() *net.UDPAddr {
+	giaddr, ciaddr := p.Header.GIAddr, p.Header.CIAddr
+	switch {
+	case giaddr != [4]byte{}:
+		return &net.UDPAddr{IP: giaddr[:], Port: 67}
+	case ciaddr != [4]byte{} && !p.Is(MessageTypeNak):
+		return &net.UDPAddr{IP: ciaddr[:], Port: 68}
+	}
+	return &net.UDPAddr{IP: net.IPv4bcast, Port: 68}
+}
@@ -0,0 +1,406 @@
+package pkt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+)
+
+// OptionCode is a DHCP option code as defined in RFC 2132
+type OptionCode uint8
+
+const (
+	OptionPad                         OptionCode = 0
+	OptionSubnetMask                  OptionCode = 1
+	OptionTimeOffset                  OptionCode = 2
+	OptionRouter                      OptionCode = 3
+	OptionTimeServer                  OptionCode = 4
+	OptionNameServer                  OptionCode = 5
+	OptionDomainNameServer            OptionCode = 6
+	OptionLogServer                   OptionCode = 7
+	OptionCookieServer                OptionCode = 8
+	OptionLPRServer                   OptionCode = 9
+	OptionImpressServer               OptionCode = 10
+	OptionResourceLocationServer      OptionCode = 11
+	OptionHostName                    OptionCode = 12
+	OptionBootFileSize                OptionCode = 13
+	OptionMeritDumpFile               OptionCode = 14
+	OptionDomainName                  OptionCode = 15
+	OptionSwapServer                  OptionCode = 16
+	OptionRootPath                    OptionCode = 17
+	OptionExtensionsPath              OptionCode = 18
+	OptionIPForwarding                OptionCode = 19
+	OptionNonLocalSourceRouting       OptionCode = 20
+	OptionPolicyFilter                OptionCode = 21
+	OptionMaxDatagramReassemblySize   OptionCode = 22
+	OptionDefaultIPTTL                OptionCode = 23
+	OptionPathMTUAgingTimeout         OptionCode = 24
+	OptionPathMTUPlateauTable         OptionCode = 25
+	OptionInterfaceMTU                OptionCode = 26
+	OptionAllSubnetsAreLocal          OptionCode = 27
+	OptionBroadcastAddress            OptionCode = 28
+	OptionPerformMaskDiscovery        OptionCode = 29
+	OptionMaskSupplier                OptionCode = 30
+	OptionPerformRouterDiscovery      OptionCode = 31
+	OptionRouterSolicitationAddress   OptionCode = 32
+	OptionStaticRoute                 OptionCode = 33
+	OptionTrailerEncapsulation        OptionCode = 34
+	OptionARPCacheTimeout             OptionCode = 35
+	OptionEthernetEncapsulation       OptionCode = 36
+	OptionTCPDefaultTTL               OptionCode = 37
+	OptionTCPKeepaliveInterval        OptionCode = 38
+	OptionTCPKeepaliveGarbage         OptionCode = 39
+	OptionNISDomain                   OptionCode = 40
+	OptionNISServers                  OptionCode = 41
+	OptionNTPServers                  OptionCode = 42
+	OptionVendorSpecific              OptionCode = 43
+	OptionNetBIOSNameServer           OptionCode = 44
+	OptionNetBIOSDatagramDistribution OptionCode = 45
+	OptionNetBIOSNodeType             OptionCode = 46
+	OptionNetBIOSScope                OptionCode = 47
+	OptionXWindowFontServer           OptionCode = 48
+	OptionXWindowDisplayManager       OptionCode = 49
+	OptionRequestedIPAddress          OptionCode = 50
+	OptionIPAddressLeaseTime          OptionCode = 51
+	OptionOverload                    OptionCode = 52
+	OptionDHCPMessageType             OptionCode = 53
+	OptionServerIdentifier            OptionCode = 54
+	OptionParameterRequestList        OptionCode = 55
+	OptionMessage                     OptionCode = 56
+	OptionMaxDHCPMessageSize          OptionCode = 57
+	OptionRenewalTime                 OptionCode = 58
+	OptionRebindingTime               OptionCode = 59
+	OptionVendorClassIdentifier       OptionCode = 60
+	OptionClientIdentifier            OptionCode = 61
+	OptionNISPlusDomain               OptionCode = 64
+	OptionNISPlusServers              OptionCode = 65
+	OptionTFTPServerName              OptionCode = 66
+	OptionBootfileName                OptionCode = 67
+	OptionMobileIPHomeAgent           OptionCode = 68
+	OptionSMTPServer                  OptionCode = 69
+	OptionPOP3Server                  OptionCode = 70
+	OptionNNTPServer                  OptionCode = 71
+	OptionWWWServer                   OptionCode = 72
+	OptionFingerServer                OptionCode = 73
+	OptionIRCServer                   OptionCode = 74
+	OptionStreetTalkServer            OptionCode = 75
+	OptionSTDAServer                  OptionCode = 76
+	OptionEnd                         OptionCode = 255
+)
+
+// OptionKind describes how the data of an option is encoded
+type OptionKind int
+
+const (
+	KindBytes           OptionKind = iota // opaque bytes
+	KindNone                              // no data (pad, end)
+	KindIP                                // single IPv4 address
+	KindIPList                            // list of IPv4 addresses
+	KindIPPairs                           // list of IPv4 address pairs
+	KindUint8                             // 1 byte unsigned integer
+	KindUint16                            // 2 byte unsigned integer
+	KindUint16List                        // list of 2 byte unsigned integers
+	KindInt32                             // 4 byte signed integer
+	KindDuration                          // 4 byte unsigned integer in seconds
+	KindBool                              // 1 byte flag
+	KindString                            // NVT ASCII string
+	KindCodeList                          // list of option codes
+	KindDomainList                        // compressed list of domain names
+	KindClasslessRoutes                   // list of classless static routes
+)
+
+// OptionInfo describes a known option code
+type OptionInfo struct {
+	Code OptionCode
+	Name string
+	Kind OptionKind
+}
+
+var optionRegistry = map[OptionCode]OptionInfo{}
+
+func registerOptions(infos ...OptionInfo) {
+	for _, info := range infos {
+		optionRegistry[info.Code] = info
+	}
+}
+
+func init() {
+	registerOptions(
+		OptionInfo{OptionPad, "Pad", KindNone},
+		OptionInfo{OptionSubnetMask, "Subnet Mask", KindIP},
+		OptionInfo{OptionTimeOffset, "Time Offset", KindInt32},
+		OptionInfo{OptionRouter, "Router", KindIPList},
+		OptionInfo{OptionTimeServer, "Time Server", KindIPList},
+		OptionInfo{OptionNameServer, "Name Server", KindIPList},
+		OptionInfo{OptionDomainNameServer, "Domain Name Server", KindIPList},
+		OptionInfo{OptionLogServer, "Log Server", KindIPList},
+		OptionInfo{OptionCookieServer, "Cookie Server", KindIPList},
+		OptionInfo{OptionLPRServer, "LPR Server", KindIPList},
+		OptionInfo{OptionImpressServer, "Impress Server", KindIPList},
+		OptionInfo{OptionResourceLocationServer, "Resource Location Server", KindIPList},
+		OptionInfo{OptionHostName, "Host Name", KindString},
+		OptionInfo{OptionBootFileSize, "Boot File Size", KindUint16},
+		OptionInfo{OptionMeritDumpFile, "Merit Dump File", KindString},
+		OptionInfo{OptionDomainName, "Domain Name", KindString},
+		OptionInfo{OptionSwapServer, "Swap Server", KindIP},
+		OptionInfo{OptionRootPath, "Root Path", KindString},
+		OptionInfo{OptionExtensionsPath, "Extensions Path", KindString},
+		OptionInfo{OptionIPForwarding, "IP Forwarding", KindBool},
+		OptionInfo{OptionNonLocalSourceRouting, "Non-Local Source Routing", KindBool},
+		OptionInfo{OptionPolicyFilter, "Policy Filter", KindIPPairs},
+		OptionInfo{OptionMaxDatagramReassemblySize, "Max Datagram Reassembly Size", KindUint16},
+		OptionInfo{OptionDefaultIPTTL, "Default IP TTL", KindUint8},
+		OptionInfo{OptionPathMTUAgingTimeout, "Path MTU Aging Timeout", KindDuration},
+		OptionInfo{OptionPathMTUPlateauTable, "Path MTU Plateau Table", KindUint16List},
+		OptionInfo{OptionInterfaceMTU, "Interface MTU", KindUint16},
+		OptionInfo{OptionAllSubnetsAreLocal, "All Subnets Are Local", KindBool},
+		OptionInfo{OptionBroadcastAddress, "Broadcast Address", KindIP},
+		OptionInfo{OptionPerformMaskDiscovery, "Perform Mask Discovery", KindBool},
+		OptionInfo{OptionMaskSupplier, "Mask Supplier", KindBool},
+		OptionInfo{OptionPerformRouterDiscovery, "Perform Router Discovery", KindBool},
+		OptionInfo{OptionRouterSolicitationAddress, "Router Solicitation Address", KindIP},
+		OptionInfo{OptionStaticRoute, "Static Route", KindIPPairs},
+		OptionInfo{OptionTrailerEncapsulation, "Trailer Encapsulation", KindBool},
+		OptionInfo{OptionARPCacheTimeout, "ARP Cache Timeout", KindDuration},
+		OptionInfo{OptionEthernetEncapsulation, "Ethernet Encapsulation", KindBool},
+		OptionInfo{OptionTCPDefaultTTL, "TCP Default TTL", KindUint8},
+		OptionInfo{OptionTCPKeepaliveInterval, "TCP Keepalive Interval", KindDuration},
+		OptionInfo{OptionTCPKeepaliveGarbage, "TCP Keepalive Garbage", KindBool},
+		OptionInfo{OptionNISDomain, "NIS Domain", KindString},
+		OptionInfo{OptionNISServers, "NIS Servers", KindIPList},
+		OptionInfo{OptionNTPServers, "NTP Servers", KindIPList},
+		OptionInfo{OptionVendorSpecific, "Vendor Specific Information", KindBytes},
+		OptionInfo{OptionNetBIOSNameServer, "NetBIOS Name Server", KindIPList},
+		OptionInfo{OptionNetBIOSDatagramDistribution, "NetBIOS Datagram Distribution Server", KindIPList},
+		OptionInfo{OptionNetBIOSNodeType, "NetBIOS Node Type", KindUint8},
+		OptionInfo{OptionNetBIOSScope, "NetBIOS Scope", KindString},
+		OptionInfo{OptionXWindowFontServer, "X Window Font Server", KindIPList},
+		OptionInfo{OptionXWindowDisplayManager, "X Window Display Manager", KindIPList},
+		OptionInfo{OptionRequestedIPAddress, "Requested IP Address", KindIP},
+		OptionInfo{OptionIPAddressLeaseTime, "IP Address Lease Time", KindDuration},
+		OptionInfo{OptionOverload, "Option Overload", KindUint8},
+		OptionInfo{OptionDHCPMessageType, "DHCP Message Type", KindUint8},
+		OptionInfo{OptionServerIdentifier, "Server Identifier", KindIP},
+		OptionInfo{OptionParameterRequestList, "Parameter Request List", KindCodeList},
+		OptionInfo{OptionMessage, "Message", KindString},
+		OptionInfo{OptionMaxDHCPMessageSize, "Maximum DHCP Message Size", KindUint16},
+		OptionInfo{OptionRenewalTime, "Renewal Time (T1)", KindDuration},
+		OptionInfo{OptionRebindingTime, "Rebinding Time (T2)", KindDuration},
+		OptionInfo{OptionVendorClassIdentifier, "Vendor Class Identifier", KindString},
+		OptionInfo{OptionClientIdentifier, "Client Identifier", KindBytes},
+		OptionInfo{OptionNISPlusDomain, "NIS+ Domain", KindString},
+		OptionInfo{OptionNISPlusServers, "NIS+ Servers", KindIPList},
+		OptionInfo{OptionTFTPServerName, "TFTP Server Name", KindString},
+		OptionInfo{OptionBootfileName, "Bootfile Name", KindString},
+		OptionInfo{OptionMobileIPHomeAgent, "Mobile IP Home Agent", KindIPList},
+		OptionInfo{OptionSMTPServer, "SMTP Server", KindIPList},
+		OptionInfo{OptionPOP3Server, "POP3 Server", KindIPList},
+		OptionInfo{OptionNNTPServer, "NNTP Server", KindIPList},
+		OptionInfo{OptionWWWServer, "WWW Server", KindIPList},
+		OptionInfo{OptionFingerServer, "Finger Server", KindIPList},
+		OptionInfo{OptionIRCServer, "IRC Server", KindIPList},
+		OptionInfo{OptionStreetTalkServer, "StreetTalk Server", KindIPList},
+		OptionInfo{OptionSTDAServer, "STDA Server", KindIPList},
+		OptionInfo{OptionEnd, "End", KindNone},
+	)
+}
+
+// LookupOption returns the registry entry for an option code
+func LookupOption(code OptionCode) (OptionInfo, bool) {
+	info, ok := optionRegistry[code]
+	return info, ok
+}
+
+func (c OptionCode) String() string {
+	if info, ok := optionRegistry[c]; ok {
+		return info.Name
+	}
+	return fmt.Sprintf("Option %d", uint8(c))
+}
+
+// Get returns the first option with the given code
+func (o *Options) Get(code OptionCode) (Option, bool) {
+	for _, opt := range o.Options {
+		if opt.Type == code {
+			return opt, true
+		}
+	}
+	return Option{}, false
+}
+
+// Has reports whether an option with the given code is present
+func (o *Options) Has(code OptionCode) bool {
+	_, ok := o.Get(code)
+	return ok
+}
+
+// Set replaces the option with the same code, or adds it before the End option
+func (o *Options) Set(opt Option) {
+	for i := range o.Options {
+		if o.Options[i].Type == opt.Type {
+			o.Options[i] = opt
+			return
+		}
+	}
+	for i := range o.Options {
+		if o.Options[i].Type == OptionEnd {
+			o.Options = append(o.Options[:i+1], o.Options[i:]...)
+			o.Options[i] = opt
+			return
+		}
+	}
+	o.Options = append(o.Options, opt)
+}
+
+// Delete removes every option with the given code
+func (o *Options) Delete(code OptionCode) {
+	opts := o.Options[:0]
+	for _, opt := range o.Options {
+		if opt.Type != code {
+			opts = append(opts, opt)
+		}
+	}
+	o.Options = opts
+}
+
+// NewOption creates an option with the given data
+func NewOption(code OptionCode, data []byte) Option {
+	return Option{
+		Type: code,
+		Data: data,
+	}
+}
+
+// GetBytes returns the raw data of an option
+func (o *Options) GetBytes(code OptionCode) ([]byte, bool) {
+	opt, ok := o.Get(code)
+	if !ok {
+		return nil, false
+	}
+	return opt.Data, true
+}
+
+// SetBytes sets the raw data of an option
+func (o *Options) SetBytes(code OptionCode, data []byte) {
+	o.Set(NewOption(code, data))
+}
+
+// GetIP returns an option holding a single IPv4 address
+func (o *Options) GetIP(code OptionCode) (net.IP, bool) {
+	data, ok := o.GetBytes(code)
+	if !ok || len(data) != net.IPv4len {
+		return nil, false
+	}
+	return net.IPv4(data[0], data[1], data[2], data[3]), true
+}
+
+// SetIP sets an option holding a single IPv4 address
+func (o *Options) SetIP(code OptionCode, ip net.IP) {
+	o.SetBytes(code, []byte(ip.To4()))
+}
+
+// GetIPs returns an option holding a list of IPv4 addresses
+func (o *Options) GetIPs(code OptionCode) ([]net.IP, bool) {
+	data, ok := o.GetBytes(code)
+	if !ok || len(data) == 0 || len(data)%net.IPv4len != 0 {
+		return nil, false
+	}
+	ips := make([]net.IP, 0, len(data)/net.IPv4len)
+	for i := 0; i < len(data); i += net.IPv4len {
+		ips = append(ips, net.IPv4(data[i], data[i+1], data[i+2], data[i+3]))
+	}
+	return ips, true
+}
+
+// SetIPs sets an option holding a list of IPv4 addresses
+func (o *Options) SetIPs(code OptionCode, ips []net.IP) {
+	data := make([]byte, 0, len(ips)*net.IPv4len)
+	for _, ip := range ips {
+		data = append(data, ip.To4()...)
+	}
+	o.SetBytes(code, data)
+}
+
+// GetDuration returns an option holding a time in seconds
+func (o *Options) GetDuration(code OptionCode) (time.Duration, bool) {
+	data, ok := o.GetBytes(code)
+	if !ok || len(data) != 4 {
+		return 0, false
+	}
+	return time.Duration(binary.BigEndian.Uint32(data)) * time.Second, true
+}
+
+// SetDuration sets an option holding a time in seconds
+func (o *Options) SetDuration(code OptionCode, d time.Duration) {
+	data := make([]byte, 4)
+	binary.BigEndian.PutUint32(data, uint32(d/time.Second))
+	o.SetBytes(code, data)
+}
+
+// GetString returns an option holding a string
+func (o *Options) GetString(code OptionCode) (string, bool) {
+	data, ok := o.GetBytes(code)
+	if !ok {
+		return "", false
+	}
+	return string(data), true
+}
+
+// SetString sets an option holding a string
+func (o *Options) SetString(code OptionCode, s string) {
+	o.SetBytes(code, []byte(s))
+}
+
+// GetCodes returns an option holding a list of option codes,
+// such as the parameter request list
+func (o *Options) GetCodes(code OptionCode) ([]OptionCode, bool) {
+	data, ok := o.GetBytes(code)
+	if !ok {
+		return nil, false
+	}
+	codes := make([]OptionCode, len(data))
+	for i := range data {
+		codes[i] = OptionCode(data[i])
+	}
+	return codes, true
+}
+
+// SetCodes sets an option holding a list of option codes
+func (o *Options) SetCodes(code OptionCode, codes []OptionCode) {
+	data := make([]byte, len(codes))
+	for i := range codes {
+		data[i] = byte(codes[i])
+	}
+	o.SetBytes(code, data)
+}
+
+// GetUint8 returns an option holding a single byte value
+func (o *Options) GetUint8(code OptionCode) (uint8, bool) {
+	data, ok := o.GetBytes(code)
+	if !ok || len(data) != 1 {
+		return 0, false
+	}
+	return data[0], true
+}
+
+// SetUint8 sets an option holding a single byte value
+func (o *Options) SetUint8(code OptionCode, v uint8) {
+	o.SetBytes(code, []byte{v})
+}
+
+// GetUint16 returns an option holding a 2 byte value
+func (o *Options) GetUint16(code OptionCode) (uint16, bool) {
+	data, ok := o.GetBytes(code)
+	if !ok || len(data) != 2 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint16(data), true
+}
+
+// SetUint16 sets an option holding a 2 byte value
+func (o *Options) SetUint16(code OptionCode, v uint16) {
+	data := make([]byte, 2)
+	binary.BigEndian.PutUint16(data, v)
+	o.SetBytes(code, data)
+}
@@ -0,0 +1,190 @@
+package pkt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Option overload values (RFC 2132 section 9.3)
+const (
+	OverloadFile  = 1
+	OverloadSName = 2
+	OverloadBoth  = 3
+)
+
+// Offsets of the overloadable header fields
+const (
+	snameOffset = 44
+	fileOffset  = 108
+)
+
+// MinMessageSize is the smallest IP datagram every DHCP client must accept
+const MinMessageSize = 576
+
+// ipUDPHeaderLen is the size of the IPv4 and UDP headers counted by option 57
+const ipUDPHeaderLen = 28
+
+// DefaultMaxSize is the largest DHCP message, excluding IP and UDP headers,
+// every client must accept
+const DefaultMaxSize = MinMessageSize - ipUDPHeaderLen
+
+var ErrMessageTooLarge = errors.New("options do not fit in maximum message size")
+
+// ClientMaxSize returns the largest DHCP message, excluding IP and UDP
+// headers, the sender of p is willing to accept. Without option 57 this
+// is the RFC 2131 minimum.
+func (p *Pkt) ClientMaxSize() int {
+	size, ok := p.Options.GetUint16(OptionMaxDHCPMessageSize)
+	if !ok || size < MinMessageSize {
+		return DefaultMaxSize
+	}
+	return int(size) - ipUDPHeaderLen
+}
+
+// decodeOverload moves options stored in the file and sname fields into
+// p.Options, in the order required by RFC 2131, and clears those fields
+func (p *Pkt) decodeOverload() error {
+	v, ok := p.Options.GetUint8(OptionOverload)
+	if !ok {
+		return nil
+	}
+	hadEnd := p.Options.Has(OptionEnd)
+	p.Options.Delete(OptionEnd)
+	p.Options.Delete(OptionOverload)
+
+	var extra Options
+	if v&OverloadFile != 0 {
+		err := extra.decode(bytes.Clone(p.Header.File[:]), fileOffset)
+		if err != nil {
+			return fmt.Errorf("failed to decode file field options: %w", err)
+		}
+		p.Header.File = [128]byte{}
+	}
+	if v&OverloadSName != 0 {
+		err := extra.decode(bytes.Clone(p.Header.SName[:]), snameOffset)
+		if err != nil {
+			return fmt.Errorf("failed to decode sname field options: %w", err)
+		}
+		p.Header.SName = [64]byte{}
+	}
+	extra.Delete(OptionEnd)
+
+	for _, opt := range extra.Options {
+		p.Options.concat(opt)
+	}
+	if hadEnd {
+		p.Options.Add(NewOptionEnd())
+	}
+	return nil
+}
+
+// area is a field options are placed in by marshalOverload
+type area struct {
+	buf  []byte
+	size int
+}
+
+// free returns the most option data that still fits in a
+func (a *area) free() int {
+	return a.size - len(a.buf) - 2
+}
+
+// add appends one option instance holding data to a
+func (a *area) add(code OptionCode, data []byte) {
+	a.buf = append(a.buf, byte(code), byte(len(data)))
+	a.buf = append(a.buf, data...)
+}
+
+// marshalOverload encodes p into at most size bytes, spilling options
+// that do not fit in the options field into the file and sname fields
+func (p *Pkt) marshalOverload(size int) ([]byte, error) {
+	// The options field keeps room for the overload option and End
+	main := &area{size: size - headerLen - 3 - 1}
+	file := &area{}
+	sname := &area{}
+	if p.Header.File == [128]byte{} {
+		file.size = len(p.Header.File) - 1
+	}
+	if p.Header.SName == [64]byte{} {
+		sname.size = len(p.Header.SName) - 1
+	}
+
+	// Options are placed piece by piece (RFC 3396), in the order the
+	// receiver concatenates them: options, then file, then sname. A piece
+	// goes whole into the first area with room for it, and only a piece
+	// that fits in none is split across the space left.
+	areas := []*area{main, file, sname}
+	for _, opt := range p.Options.Options {
+		if opt.Type == OptionEnd || opt.Type == OptionOverload || opt.Type == OptionPad {
+			continue
+		}
+		next := 0
+		for _, piece := range opt.pieces() {
+			i := next
+			for i < len(areas) && areas[i].free() < len(piece.Data) {
+				i++
+			}
+			if i < len(areas) {
+				areas[i].add(piece.Type, piece.Data)
+				next = i
+				continue
+			}
+			if len(piece.Data) == 0 {
+				return nil, fmt.Errorf("%w: %v", ErrMessageTooLarge, opt.Type)
+			}
+			for data := piece.Data; len(data) > 0; {
+				if next == len(areas) {
+					return nil, fmt.Errorf("%w: %v", ErrMessageTooLarge, opt.Type)
+				}
+				n := min(len(data), areas[next].free())
+				if n <= 0 {
+					next++
+					continue
+				}
+				areas[next].add(piece.Type, data[:n])
+				data = data[n:]
+			}
+		}
+	}
+
+	var overload uint8
+	header := p.Header
+	if len(file.buf) > 0 {
+		overload |= OverloadFile
+		header.File = [128]byte{}
+		copy(header.File[:], append(file.buf, byte(OptionEnd)))
+	}
+	if len(sname.buf) > 0 {
+		overload |= OverloadSName
+		header.SName = [64]byte{}
+		copy(header.SName[:], append(sname.buf, byte(OptionEnd)))
+	}
+
+	var buf bytes.Buffer
+	err := binary.Write(&buf, binary.BigEndian, &header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write header: %w", err)
+	}
+	if overload != 0 {
+		buf.Write([]byte{byte(OptionOverload), 1, overload})
+	}
+	buf.Write(main.buf)
+	buf.WriteByte(byte(OptionEnd))
+	return buf.Bytes(), nil
+}
+
+// pieces splits o into instances of at most maxOptionLen bytes of data
+func (o Option) pieces() []Option {
+	if len(o.Data) <= maxOptionLen {
+		return []Option{o}
+	}
+	var pieces []Option
+	for data := o.Data; len(data) > 0; {
+		n := min(len(data), maxOptionLen)
+		pieces = append(pieces, Option{Type: o.Type, Data: data[:n:n]})
+		data = data[n:]
+	}
+	return pieces
+}
@@ -0,0 +1,82 @@
+package pkt
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+// overloadOffer returns a DHCPOFFER limited to the RFC 2131 minimum size
+func overloadOffer() *Pkt {
+	p := &Pkt{
+		Header: Header{
+			OpCode: OpCodeBootReply,
+			HType:  HTypeEthernet,
+			XID:    0x3903f326,
+			YIAddr: [4]byte{192, 168, 0, 10},
+			Cookie: [4]byte(dhcpMagicCookie),
+		},
+		MaxSize: DefaultMaxSize,
+	}
+	p.SetCHAddr(net.HardwareAddr{0x00, 0x0b, 0x82, 0x01, 0xfc, 0x42})
+	p.Options.Add(NewOptionMessageType(MessageTypeOffer))
+	p.Options.Add(NewOptionServerID(net.IPv4(192, 168, 0, 1)))
+	return p
+}
+
+func TestMarshalOverloadSplitsLongOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{
+			name: "option longer than 255 bytes",
+			opts: []Option{
+				NewOption(OptionVendorSpecific, bytes.Repeat([]byte{0xab}, 300)),
+			},
+		},
+		{
+			name: "options spilling into file and sname",
+			opts: []Option{
+				NewOption(OptionVendorSpecific, bytes.Repeat([]byte{0xab}, 250)),
+				NewOption(OptionDomainName, bytes.Repeat([]byte{'a'}, 200)),
+			},
+		},
+		{
+			name: "option longer than the options field",
+			opts: []Option{
+				NewOption(OptionVendorSpecific, bytes.Repeat([]byte{0xcd}, 400)),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := overloadOffer()
+			for _, opt := range tt.opts {
+				p.Options.Add(opt)
+			}
+			p.Options.Add(NewOptionEnd())
+
+			b, err := p.MarshalBinary()
+			if err != nil {
+				t.Fatalf("failed to marshal: %v", err)
+			}
+			if len(b) > DefaultMaxSize {
+				t.Fatalf("got %d bytes, want at most %d", len(b), DefaultMaxSize)
+			}
+			var q Pkt
+			if err := q.UnmarshalBinary(b); err != nil {
+				t.Fatalf("failed to unmarshal: %v", err)
+			}
+			for _, want := range tt.opts {
+				got, ok := q.Options.GetBytes(want.Type)
+				if !ok {
+					t.Fatalf("option %v missing", want.Type)
+				}
+				if !bytes.Equal(got, want.Data) {
+					t.Fatalf("option %v has %d bytes, want %d intact", want.Type, len(got), len(want.Data))
+				}
+			}
+		})
+	}
+}
@@ -8,15 +8,23 @@ import (
 	"io"
 	"net"
 	"strings"
+)
 
-	packetreader "github.com/jon-ski/dhcpset/internal/packet-reader"
+const (
+	OpCodeBootRequest = 0x01
+	OpCodeBootReply   = 0x02
 )
 
+// Hardware types (RFC 1700 / IANA ARP parameters)
 const (
-	MessageTypeOffer = 2
-	MessageTypeAck   = 5
+	HTypeEthernet   = 1
+	HTypeIEEE802    = 6
+	HTypeInfiniBand = 32
 )
 
+// FlagBroadcast asks the server to broadcast its replies (RFC 1542)
+const FlagBroadcast = 0x8000
+
 var dhcpMagicCookie = []byte{0x63, 0x82, 0x53, 0x63}
 
 var ErrInvalidPacket = errors.New("invalid packet")
@@ -62,51 +70,129 @@ func (o *Options) MarshalBinary() ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// Option is a single DHCP option. The length on the wire is always
+// derived from Data.
 type Option struct {
-	Type   byte
-	Length byte
-	Data   []byte
+	Type OptionCode
+	Data []byte
+}
+
+// maxOptionLen is the most data a single option instance can carry
+const maxOptionLen = 255
+
+// Len returns the length of the option data
+func (o *Option) Len() int {
+	return len(o.Data)
 }
 
+// MarshalBinary encodes the option. Data longer than 255 bytes is split
+// into consecutive instances of the same option as described in RFC 3396.
 func (o *Option) MarshalBinary() ([]byte, error) {
-	buf := make([]byte, 2+len(o.Data))
-	buf[0] = o.Type
-	buf[1] = o.Length
-	copy(buf[2:], o.Data)
+	if o.Type == OptionPad || o.Type == OptionEnd {
+		return []byte{byte(o.Type)}, nil
+	}
+	data := o.Data
+	buf := make([]byte, 0, 2+len(data)+2*(len(data)/maxOptionLen))
+	for {
+		n := min(len(data), maxOptionLen)
+		buf = append(buf, byte(o.Type), byte(n))
+		buf = append(buf, data[:n]...)
+		data = data[n:]
+		if len(data) == 0 {
+			break
+		}
+	}
 	return buf, nil
 }
 
+// Decode reads options until the End option or the end of r
 func (o *Options) Decode(r io.Reader) error {
-	for {
-		var opt Option
-		err := opt.Decode(r)
-		if err != nil {
-			return fmt.Errorf("failed to decode option: %w", err)
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read options: %w", err)
+	}
+	return o.decode(b, 0)
+}
+
+// decode parses the options in b. base is the offset of b within the
+// packet and is only used for error reporting. Pad options are skipped
+// and a missing End option is accepted. Option data shares b.
+func (o *Options) decode(b []byte, base int) error {
+	for i := 0; i < len(b); {
+		code := OptionCode(b[i])
+		switch code {
+		case OptionPad:
+			i++
+			continue
+		case OptionEnd:
+			o.Options = append(o.Options, NewOptionEnd())
+			return nil
 		}
-		o.Options = append(o.Options, opt)
-		if opt.Type == 0xff {
-			break
+		if i+1 >= len(b) {
+			return parseErr(base+i, ErrTruncated)
 		}
+		n := int(b[i+1])
+		if i+2+n > len(b) {
+			return parseErr(base+i+1, ErrBadOptionLength)
+		}
+		o.concat(Option{
+			Type: code,
+			Data: b[i+2 : i+2+n : i+2+n],
+		})
+		i += 2 + n
 	}
 	return nil
 }
 
+// concat adds opt, or appends its data to an option with the same code
+// that is already present, as described in RFC 3396
+func (o *Options) concat(opt Option) {
+	for i := range o.Options {
+		if o.Options[i].Type == opt.Type {
+			o.Options[i].Data = append(o.Options[i].Data, opt.Data...)
+			return
+		}
+	}
+	o.Options = append(o.Options, opt)
+}
+
+// Decode reads a single option from r. Pad and End options have no length byte.
 func (o *Option) Decode(r io.Reader) error {
 	header := make([]byte, 2)
-	_, err := r.Read(header)
+	_, err := io.ReadFull(r, header[:1])
 	if err != nil {
 		return err
 	}
-	o.Type = header[0]
-	o.Length = header[1]
-	o.Data = make([]byte, o.Length)
-	_, err = r.Read(o.Data)
-	return err
+	o.Type = OptionCode(header[0])
+	o.Data = nil
+	if o.Type == OptionPad || o.Type == OptionEnd {
+		return nil
+	}
+	_, err = io.ReadFull(r, header[1:])
+	if err != nil {
+		return ErrTruncated
+	}
+	o.Data = make([]byte, header[1])
+	_, err = io.ReadFull(r, o.Data)
+	if err != nil {
+		return ErrBadOptionLength
+	}
+	return nil
 }
 
 type Pkt struct {
 	Header  Header
 	Options Options
+
+	// MaxSize is the largest encoded message the receiver accepts, usually
+	// taken from the request with ClientMaxSize. Options that do not fit are
+	// spilled into the file and sname fields. Zero means no limit.
+	MaxSize int
+
+	// Vendor is the raw vendor area of a BOOTP packet that does not start
+	// with the magic cookie (RFC 951). Such packets have no options and
+	// Vendor replaces the cookie and options when encoding.
+	Vendor []byte
 }
 
 func NewPkt() *Pkt {
@@ -122,19 +208,34 @@ func NewFromBytes(b []byte) (*Pkt, error) {
 	return pkt, nil
 }
 
+// headerLen is the length of the fixed BOOTP header including the magic cookie
+const headerLen = 240
+
+// cookieOffset is the offset of the magic cookie in the header
+const cookieOffset = 236
+
+// UnmarshalBinary decodes b. The packet does not share memory with b,
+// the options are copied in a single allocation.
 func (p *Pkt) UnmarshalBinary(b []byte) error {
-	err := binary.Read(packetreader.NewReader(b), binary.BigEndian, &p.Header)
+	v, err := NewView(b)
 	if err != nil {
-		return fmt.Errorf("failed to read header: %w", err)
+		return err
+	}
+	p.Header = v.Header()
+	p.Vendor = nil
+	if vendor := v.Vendor(); vendor != nil {
+		p.Options.Options = nil
+		p.Vendor = bytes.Clone(vendor)
+		return nil
 	}
 
 	// Decode options
-	p.Options.Options = make([]Option, 0)
-	err = p.Options.Decode(strings.NewReader(string(b[240:])))
+	p.Options.Options = make([]Option, 0, 16)
+	err = p.Options.decode(bytes.Clone(b[headerLen:]), headerLen)
 	if err != nil {
 		return fmt.Errorf("failed to decode options: %w", err)
 	}
-	return nil
+	return p.decodeOverload()
 }
 
 func (p *Pkt) MarshalBinary() ([]byte, error) {
@@ -143,16 +244,27 @@ func (p *Pkt) MarshalBinary() ([]byte, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to write header: %w", err)
 	}
+	if p.Vendor != nil {
+		buf.Truncate(cookieOffset)
+		buf.Write(p.Vendor)
+		return padBOOTP(buf.Bytes()), nil
+	}
 
 	// Marshal options
 	options, err := p.Options.MarshalBinary()
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal options: %w", err)
 	}
+	if p.MaxSize > 0 && headerLen+len(options) > p.MaxSize {
+		return p.marshalOverload(p.MaxSize)
+	}
 	_, err = buf.Write(options)
 	if err != nil {
 		return nil, fmt.Errorf("failed to write options: %w", err)
 	}
+	if p.IsBOOTP() {
+		return padBOOTP(buf.Bytes()), nil
+	}
 
 	return buf.Bytes(), nil
 }
@@ -160,9 +272,10 @@ func (p *Pkt) MarshalBinary() ([]byte, error) {
 func (p *Pkt) PrintMAC() string {
 	// format and print the mac address
 	b := strings.Builder{}
-	for i := 0; i < int(p.Header.HLen); i++ {
+	n := min(int(p.Header.HLen), len(p.Header.CHAddr))
+	for i := 0; i < n; i++ {
 		b.WriteString(fmt.Sprintf("%02x", p.Header.CHAddr[i]))
-		if i < int(p.Header.HLen)-1 {
+		if i < n-1 {
 			b.WriteString(":")
 		}
 	}
@@ -171,56 +284,48 @@ func (p *Pkt) PrintMAC() string {
 
 func (p *Pkt) PrintName() string {
 	// Get name from options
-	for _, opt := range p.Options.Options {
-		if opt.Type == 12 && opt.Length > 0 {
-			return string(opt.Data)
-		}
+	name, ok := p.Options.GetString(OptionHostName)
+	if !ok || name == "" {
+		return "unknown"
 	}
-	return "unknown"
+	return name
 }
 
+// SetCHAddr sets chaddr and hlen to addr, truncated to 16 bytes
 func (p *Pkt) SetCHAddr(addr net.HardwareAddr) {
-	// Set the length of the hardware address
-	if len(addr) > 16 {
-		p.Header.HLen = 16
-		addr = addr[:16]
+	n := min(len(addr), len(p.Header.CHAddr))
+	p.Header.HLen = uint8(n)
+	p.Header.CHAddr = [16]byte{}
+	copy(p.Header.CHAddr[:], addr[:n])
+}
+
+// SetHardwareAddr sets the hardware type and address of the client.
+// InfiniBand addresses do not fit in chaddr, which is left empty as
+// required by RFC 4390; such clients identify themselves with option 61.
+func (p *Pkt) SetHardwareAddr(htype uint8, addr net.HardwareAddr) {
+	p.Header.HType = htype
+	if htype == HTypeInfiniBand {
+		addr = nil
 	}
-	p.Header.HLen = uint8(len(addr))
-	copy(p.Header.CHAddr[:], addr)
+	p.SetCHAddr(addr)
 }
 
 func (o *Options) Add(opt Option) {
 	o.Options = append(o.Options, opt)
 }
 
-func NewOptionMessageType(t uint8) Option {
-	return Option{
-		Type:   53,
-		Length: 1,
-		Data:   []byte{t},
-	}
+func NewOptionMessageType(t MessageType) Option {
+	return NewOption(OptionDHCPMessageType, []byte{byte(t)})
 }
 
 func NewOptionServerID(ip net.IP) Option {
-	return Option{
-		Type:   54,
-		Length: 4,
-		Data:   []byte(ip.To4()),
-	}
+	return NewOption(OptionServerIdentifier, []byte(ip.To4()))
 }
 
 func NewOptionSubnetMask(mask net.IPMask) Option {
-	return Option{
-		Type:   1,
-		Length: 4,
-		Data:   []byte(mask),
-	}
+	return NewOption(OptionSubnetMask, []byte(mask))
 }
 
 func NewOptionEnd() Option {
-	return Option{
-		Type:   0xff,
-		Length: 0,
-		Data:   nil,
-	}
+	return NewOption(OptionEnd, nil)
 }
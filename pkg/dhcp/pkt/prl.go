@@ -0,0 +1,45 @@
+package pkt
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+// InfiniteLease is the lease time that never expires (RFC 2131 section 3.3)
+const InfiniteLease = time.Duration(math.MaxUint32) * time.Second
+
+// RequestedOptions returns the client's parameter request list (option 55)
+func (p *Pkt) RequestedOptions() ([]OptionCode, bool) {
+	return p.Options.GetCodes(OptionParameterRequestList)
+}
+
+// SelectOptions picks the options of available to send to a client.
+// Options in prl are returned in the order the client asked for them,
+// followed by any mandatory options the client did not ask for. When the
+// client sent no parameter request list (prl is nil) every available
+// option is returned.
+func SelectOptions(available Options, prl []OptionCode, mandatory ...OptionCode) []Option {
+	if prl == nil {
+		return slices.Clone(available.Options)
+	}
+	var selected []Option
+	added := make(map[OptionCode]bool)
+	for _, code := range prl {
+		opt, ok := available.Get(code)
+		if !ok || added[code] {
+			continue
+		}
+		selected = append(selected, opt)
+		added[code] = true
+	}
+	for _, code := range mandatory {
+		opt, ok := available.Get(code)
+		if !ok || added[code] {
+			continue
+		}
+		selected = append(selected, opt)
+		added[code] = true
+	}
+	return selected
+}
@@ -0,0 +1,133 @@
+package pkt
+
+import (
+	"fmt"
+	"net"
+	"unicode"
+)
+
+// OptionRelayAgentInfo is the relay agent information option (RFC 3046)
+const OptionRelayAgentInfo OptionCode = 82
+
+// Relay agent information sub-option codes
+const (
+	RelayCircuitID     OptionCode = 1 // RFC 3046
+	RelayRemoteID      OptionCode = 2 // RFC 3046
+	RelayLinkSelection OptionCode = 5 // RFC 3527
+	RelaySubscriberID  OptionCode = 6 // RFC 3993
+)
+
+// RelayAgentSpace is the relay agent information space (option 82)
+var RelayAgentSpace = OptionSpace{
+	Name: "Relay Agent Information",
+	SubOptions: map[OptionCode]string{
+		RelayCircuitID:     "Circuit ID",
+		RelayRemoteID:      "Remote ID",
+		RelayLinkSelection: "Link Selection",
+		RelaySubscriberID:  "Subscriber ID",
+	},
+}
+
+func init() {
+	registerOptions(OptionInfo{OptionRelayAgentInfo, "Relay Agent Information", KindBytes})
+	RegisterOptionSpace(OptionRelayAgentInfo, RelayAgentSpace)
+}
+
+// RelayAgentInfo holds the sub-options of option 82
+type RelayAgentInfo struct {
+	CircuitID     []byte
+	RemoteID      []byte
+	LinkSelection net.IP
+	SubscriberID  string
+
+	// Other holds sub-options without a field above, in packet order
+	Other []Option
+}
+
+// ParseRelayAgentInfo decodes the data of option 82
+func ParseRelayAgentInfo(data []byte) (RelayAgentInfo, error) {
+	var r RelayAgentInfo
+	subs, err := RelayAgentSpace.Decode(data)
+	if err != nil {
+		return r, err
+	}
+	for _, sub := range subs.Options {
+		switch sub.Type {
+		case RelayCircuitID:
+			r.CircuitID = sub.Data
+		case RelayRemoteID:
+			r.RemoteID = sub.Data
+		case RelayLinkSelection:
+			if len(sub.Data) != net.IPv4len {
+				return r, fmt.Errorf("%w: link selection", ErrBadOptionLength)
+			}
+			r.LinkSelection = net.IP(sub.Data)
+		case RelaySubscriberID:
+			r.SubscriberID = string(sub.Data)
+		default:
+			r.Other = append(r.Other, sub)
+		}
+	}
+	return r, nil
+}
+
+// MarshalBinary encodes the sub-options as the data of option 82
+func (r RelayAgentInfo) MarshalBinary() ([]byte, error) {
+	var subs Options
+	if len(r.CircuitID) > 0 {
+		subs.Add(NewOption(RelayCircuitID, r.CircuitID))
+	}
+	if len(r.RemoteID) > 0 {
+		subs.Add(NewOption(RelayRemoteID, r.RemoteID))
+	}
+	if r.LinkSelection != nil {
+		subs.Add(NewOption(RelayLinkSelection, r.LinkSelection.To4()))
+	}
+	if r.SubscriberID != "" {
+		subs.Add(NewOption(RelaySubscriberID, []byte(r.SubscriberID)))
+	}
+	subs.Options = append(subs.Options, r.Other...)
+	return RelayAgentSpace.Encode(subs)
+}
+
+// NewOptionRelayAgentInfo creates option 82 from its sub-options
+func NewOptionRelayAgentInfo(r RelayAgentInfo) (Option, error) {
+	data, err := r.MarshalBinary()
+	if err != nil {
+		return Option{}, err
+	}
+	return NewOption(OptionRelayAgentInfo, data), nil
+}
+
+// RelayAgentInfo returns the decoded option 82 of the packet
+func (p *Pkt) RelayAgentInfo() (RelayAgentInfo, bool) {
+	data, ok := p.Options.GetBytes(OptionRelayAgentInfo)
+	if !ok {
+		return RelayAgentInfo{}, false
+	}
+	r, err := ParseRelayAgentInfo(data)
+	if err != nil {
+		return RelayAgentInfo{}, false
+	}
+	return r, true
+}
+
+// CircuitIDString formats the circuit ID for display
+func (r RelayAgentInfo) CircuitIDString() string {
+	return printableOrHex(r.CircuitID)
+}
+
+// RemoteIDString formats the remote ID for display
+func (r RelayAgentInfo) RemoteIDString() string {
+	return printableOrHex(r.RemoteID)
+}
+
+// printableOrHex returns b as text if it is printable ASCII, or as hex otherwise
+func printableOrHex(b []byte) string {
+	for _, c := range b {
+		if c > unicode.MaxASCII || !unicode.IsPrint(rune(c)) {
+			return fmt.Sprintf("%x", b)
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,281 @@
+package pkt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Validation rules reported in ValidationError.Rule
+const (
+	RuleCookie          = "cookie"
+	RuleOpCode          = "opcode"
+	RuleHardwareAddress = "hardware-address"
+	RuleMessageType     = "message-type"
+	RuleOptionLength    = "option-length"
+	RuleOptionValue     = "option-value"
+	RuleRequiredOption  = "required-option"
+	RuleForbiddenOption = "forbidden-option"
+	RuleAddress         = "address"
+)
+
+// ValidationError is a single rule broken by a packet
+type ValidationError struct {
+	Rule string
+	// Option is the option concerned, or 0 for header rules
+	Option OptionCode
+	Msg    string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Option != 0 {
+		return fmt.Sprintf("%s: option %d (%v): %s", e.Rule, uint8(e.Option), e.Option, e.Msg)
+	}
+	return fmt.Sprintf("%s: %s", e.Rule, e.Msg)
+}
+
+// ValidationErrors lists every rule broken by a packet
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// hardwareLen is the address length of hardware types with a fixed one.
+// InfiniBand addresses do not fit in chaddr and are carried in the client
+// identifier instead (RFC 4390).
+var hardwareLen = map[uint8]uint8{
+	HTypeEthernet:   6,
+	HTypeIEEE802:    6,
+	HTypeInfiniBand: 0,
+}
+
+// RequestState is the client state a DHCPREQUEST is sent from
+// (RFC 2131 section 4.3.2)
+type RequestState int
+
+const (
+	RequestSelecting  RequestState = iota + 1 // answering an OFFER
+	RequestInitReboot                         // verifying a remembered address
+	RequestRenewing                           // extending a lease, also rebinding
+)
+
+func (s RequestState) String() string {
+	switch s {
+	case RequestSelecting:
+		return "SELECTING"
+	case RequestInitReboot:
+		return "INIT-REBOOT"
+	case RequestRenewing:
+		return "RENEWING"
+	}
+	return fmt.Sprintf("RequestState(%d)", int(s))
+}
+
+// RequestState returns the state the client sent a DHCPREQUEST from
+func (p *Pkt) RequestState() RequestState {
+	switch {
+	case p.Options.Has(OptionServerIdentifier):
+		return RequestSelecting
+	case p.Header.CIAddr == [4]byte{}:
+		return RequestInitReboot
+	}
+	return RequestRenewing
+}
+
+// Validate checks p against RFC 2131 and RFC 2132: the magic cookie, the
+// opcode, the hardware address length, the length of known options and
+// the options required or forbidden for the message type. BOOTP packets
+// are only checked up to the options. It returns ValidationErrors listing
+// every broken rule, or nil.
+func (p *Pkt) Validate() error {
+	var errs ValidationErrors
+	fail := func(rule string, code OptionCode, format string, args ...any) {
+		errs = append(errs, &ValidationError{
+			Rule:   rule,
+			Option: code,
+			Msg:    fmt.Sprintf(format, args...),
+		})
+	}
+
+	switch {
+	case p.Vendor == nil && !bytes.Equal(p.Header.Cookie[:], dhcpMagicCookie):
+		fail(RuleCookie, 0, "got % x", p.Header.Cookie)
+	case p.Vendor != nil && (len(p.Vendor) < VendorLen || !bytes.HasPrefix(p.Vendor, noCookie)):
+		fail(RuleCookie, 0, "vendor area without extensions must be %d bytes starting with zeros",
+			VendorLen)
+	}
+	if p.Header.OpCode != OpCodeBootRequest && p.Header.OpCode != OpCodeBootReply {
+		fail(RuleOpCode, 0, "unknown opcode %d", p.Header.OpCode)
+	}
+	if want, ok := hardwareLen[p.Header.HType]; ok && p.Header.HLen != want {
+		fail(RuleHardwareAddress, 0, "hlen %d for %s, want %d",
+			p.Header.HLen, HardwareTypeName(p.Header.HType), want)
+	} else if p.Header.HLen > 16 {
+		fail(RuleHardwareAddress, 0, "hlen %d longer than chaddr", p.Header.HLen)
+	}
+
+	for _, opt := range p.Options.Options {
+		if rule, msg := checkOption(opt); rule != "" {
+			fail(rule, opt.Type, "%s", msg)
+		}
+	}
+
+	// plain BOOTP has no message type and no further rules
+	if p.IsBOOTP() {
+		if len(errs) == 0 {
+			return nil
+		}
+		return errs
+	}
+
+	t := p.MessageType()
+	switch {
+	case !t.Valid():
+		// reported by checkOption
+	case t.IsClientMessage() && p.Header.OpCode != OpCodeBootRequest:
+		fail(RuleOpCode, 0, "%v sent as BOOTREPLY", t)
+	case !t.IsClientMessage() && p.Header.OpCode != OpCodeBootReply:
+		fail(RuleOpCode, 0, "%v sent as BOOTREQUEST", t)
+	}
+
+	require := func(codes ...OptionCode) {
+		for _, code := range codes {
+			if !p.Options.Has(code) {
+				fail(RuleRequiredOption, code, "missing in %v", t)
+			}
+		}
+	}
+	forbid := func(codes ...OptionCode) {
+		for _, code := range codes {
+			if p.Options.Has(code) {
+				fail(RuleForbiddenOption, code, "not allowed in %v", t)
+			}
+		}
+	}
+	zeroCIAddr := func() {
+		if p.Header.CIAddr != [4]byte{} {
+			fail(RuleAddress, 0, "ciaddr must be zero in %v", t)
+		}
+	}
+
+	// chaddr is empty for InfiniBand, only the client identifier is left
+	// to tell clients apart (RFC 4390)
+	if p.Header.HType == HTypeInfiniBand && t.IsClientMessage() {
+		require(OptionClientIdentifier)
+	}
+
+	// RFC 2131 tables 3 and 5
+	switch t {
+	case MessageTypeDiscover:
+		forbid(OptionServerIdentifier)
+		zeroCIAddr()
+	case MessageTypeRequest:
+		switch p.RequestState() {
+		case RequestSelecting, RequestInitReboot:
+			require(OptionRequestedIPAddress)
+			zeroCIAddr()
+		case RequestRenewing:
+			forbid(OptionRequestedIPAddress)
+		}
+	case MessageTypeDecline:
+		require(OptionRequestedIPAddress, OptionServerIdentifier)
+		forbid(OptionIPAddressLeaseTime)
+		zeroCIAddr()
+	case MessageTypeRelease:
+		require(OptionServerIdentifier)
+		forbid(OptionRequestedIPAddress, OptionIPAddressLeaseTime)
+		if p.Header.CIAddr == [4]byte{} {
+			fail(RuleAddress, 0, "ciaddr must be set in %v", t)
+		}
+	case MessageTypeInform:
+		forbid(OptionRequestedIPAddress, OptionIPAddressLeaseTime)
+	case MessageTypeOffer:
+		require(OptionServerIdentifier, OptionIPAddressLeaseTime)
+		if p.Header.YIAddr == [4]byte{} {
+			fail(RuleAddress, 0, "yiaddr must be set in %v", t)
+		}
+	case MessageTypeAck:
+		require(OptionServerIdentifier)
+	case MessageTypeNak:
+		require(OptionServerIdentifier)
+		forbid(OptionIPAddressLeaseTime, OptionRequestedIPAddress)
+		if p.Header.YIAddr != [4]byte{} {
+			fail(RuleAddress, 0, "yiaddr must be zero in %v", t)
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// checkOption returns the rule broken by the data of a known option and
+// why, or "" if it is fine
+func checkOption(opt Option) (rule, msg string) {
+	info, ok := LookupOption(opt.Type)
+	if !ok {
+		return "", ""
+	}
+	n := len(opt.Data)
+	want, of := -1, 1
+	switch info.Kind {
+	case KindNone:
+		want = 0
+	case KindIP, KindInt32, KindDuration:
+		want = 4
+	case KindUint8, KindBool:
+		want = 1
+	case KindUint16:
+		want = 2
+	case KindIPList:
+		of = 4
+	case KindIPPairs:
+		of = 8
+	case KindUint16List:
+		of = 2
+	}
+	switch {
+	case want >= 0 && n != want:
+		return RuleOptionLength, fmt.Sprintf("length %d, want %d", n, want)
+	case want < 0 && (n == 0 || n%of != 0):
+		return RuleOptionLength, fmt.Sprintf("length %d, want a non-zero multiple of %d", n, of)
+	}
+
+	switch info.Kind {
+	case KindBool:
+		if opt.Data[0] > 1 {
+			return RuleOptionValue, fmt.Sprintf("flag %d, want 0 or 1", opt.Data[0])
+		}
+	case KindDomainList:
+		if _, err := ParseDomainSearch(opt.Data); err != nil {
+			return RuleOptionValue, err.Error()
+		}
+	case KindClasslessRoutes:
+		if _, err := ParseClasslessRoutes(opt.Data); err != nil {
+			return RuleOptionValue, err.Error()
+		}
+	}
+
+	switch opt.Type {
+	case OptionDHCPMessageType:
+		if !MessageType(opt.Data[0]).Valid() {
+			return RuleMessageType, fmt.Sprintf("unknown message type %d", opt.Data[0])
+		}
+	case OptionMaxDHCPMessageSize:
+		if size := binary.BigEndian.Uint16(opt.Data); size < MinMessageSize {
+			return RuleOptionValue, fmt.Sprintf("%d is below the minimum of %d", size, MinMessageSize)
+		}
+	case OptionClientIdentifier:
+		if n < 2 {
+			return RuleOptionLength, fmt.Sprintf("length %d, want at least 2", n)
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,264 @@
+package pkt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+)
+
+// PktView is a read only view of an encoded DHCP packet. It does not copy
+// or allocate: header fields and option data are sub slices of the
+// underlying buffer, so they are only valid until the buffer is reused.
+// Use Pkt to keep a packet around.
+type PktView struct {
+	b []byte
+	// raw is set for BOOTP packets without the magic cookie
+	raw bool
+}
+
+// NewView checks the fixed header and magic cookie of b and returns a
+// view of it. Options are parsed lazily by Options and Get. BOOTP packets
+// with a full vendor area that starts with zeros instead of the cookie
+// are taken as RFC 951 packets with a raw vendor area.
+func NewView(b []byte) (PktView, error) {
+	if len(b) < cookieOffset {
+		return PktView{}, parseErr(len(b), ErrTruncated)
+	}
+	if isRawBOOTP(b) {
+		return PktView{b: b, raw: true}, nil
+	}
+	if len(b) < headerLen {
+		return PktView{}, parseErr(len(b), ErrTruncated)
+	}
+	if !bytes.Equal(b[cookieOffset:headerLen], dhcpMagicCookie) {
+		return PktView{}, parseErr(cookieOffset, ErrBadCookie)
+	}
+	return PktView{b: b}, nil
+}
+
+// noCookie is the start of a vendor area without RFC 1048 extensions
+var noCookie = []byte{0, 0, 0, 0}
+
+// isRawBOOTP reports whether b is a BOOTREQUEST or BOOTREPLY with a full
+// vendor area and no magic cookie (RFC 951)
+func isRawBOOTP(b []byte) bool {
+	op := b[0]
+	return (op == OpCodeBootRequest || op == OpCodeBootReply) &&
+		len(b) >= bootpMinLen &&
+		bytes.Equal(b[cookieOffset:headerLen], noCookie)
+}
+
+// Bytes returns the underlying buffer
+func (v PktView) Bytes() []byte { return v.b }
+
+func (v PktView) OpCode() uint8 { return v.b[0] }
+func (v PktView) HType() uint8  { return v.b[1] }
+func (v PktView) HLen() uint8   { return v.b[2] }
+func (v PktView) Hops() uint8   { return v.b[3] }
+func (v PktView) XID() uint32   { return binary.BigEndian.Uint32(v.b[4:]) }
+func (v PktView) Secs() uint16  { return binary.BigEndian.Uint16(v.b[8:]) }
+func (v PktView) Flags() uint16 { return binary.BigEndian.Uint16(v.b[10:]) }
+
+func (v PktView) CIAddr() net.IP { return net.IP(v.b[12:16:16]) }
+func (v PktView) YIAddr() net.IP { return net.IP(v.b[16:20:20]) }
+func (v PktView) SIAddr() net.IP { return net.IP(v.b[20:24:24]) }
+func (v PktView) GIAddr() net.IP { return net.IP(v.b[24:28:28]) }
+
+// CHAddr returns the first HLen bytes of the client hardware address
+func (v PktView) CHAddr() []byte {
+	n := 28 + min(int(v.HLen()), 16)
+	return v.b[28:n:n]
+}
+
+// Header copies the fixed header out of the view
+func (v PktView) Header() Header {
+	b := v.b
+	h := Header{
+		OpCode: b[0],
+		HType:  b[1],
+		HLen:   b[2],
+		Hops:   b[3],
+		XID:    binary.BigEndian.Uint32(b[4:]),
+		Secs:   binary.BigEndian.Uint16(b[8:]),
+		Flags:  binary.BigEndian.Uint16(b[10:]),
+	}
+	copy(h.CIAddr[:], b[12:16])
+	copy(h.YIAddr[:], b[16:20])
+	copy(h.SIAddr[:], b[20:24])
+	copy(h.GIAddr[:], b[24:28])
+	copy(h.CHAddr[:], b[28:snameOffset])
+	copy(h.SName[:], b[snameOffset:fileOffset])
+	copy(h.File[:], b[fileOffset:cookieOffset])
+	if !v.raw {
+		copy(h.Cookie[:], b[cookieOffset:headerLen])
+	}
+	return h
+}
+
+// Vendor returns the raw vendor area of a BOOTP packet without the magic
+// cookie, or nil
+func (v PktView) Vendor() []byte {
+	if !v.raw {
+		return nil
+	}
+	return v.b[cookieOffset:len(v.b):len(v.b)]
+}
+
+// IsBOOTP reports whether the packet is plain BOOTP, without a DHCP
+// message type
+func (v PktView) IsBOOTP() bool {
+	_, ok := v.Get(OptionDHCPMessageType)
+	return !ok
+}
+
+// Options returns an iterator over the options of the packet, including
+// those stored in the file and sname fields when option overload is used
+func (v PktView) Options() OptionIter {
+	if v.raw {
+		return OptionIter{region: regionDone}
+	}
+	return OptionIter{
+		full: v.b,
+		b:    v.b[headerLen:],
+		base: headerLen,
+	}
+}
+
+// Get returns the data of the first instance of an option. Options split
+// into several instances (RFC 3396) are only concatenated by Pkt.
+func (v PktView) Get(code OptionCode) ([]byte, bool) {
+	it := v.Options()
+	for it.Next() {
+		if it.Code() == code {
+			return it.Data(), true
+		}
+	}
+	return nil, false
+}
+
+// MessageType returns the DHCP message type (option 53), or 0 if absent
+func (v PktView) MessageType() MessageType {
+	data, ok := v.Get(OptionDHCPMessageType)
+	if !ok || len(data) != 1 {
+		return 0
+	}
+	return MessageType(data[0])
+}
+
+// Is reports whether the packet is of the given message type, see Pkt.Is
+func (v PktView) Is(t MessageType) bool {
+	if v.MessageType() != t {
+		return false
+	}
+	if t.IsClientMessage() {
+		return v.OpCode() == OpCodeBootRequest
+	}
+	return v.OpCode() == OpCodeBootReply
+}
+
+// Pkt decodes the packet into a Pkt that does not share the buffer
+func (v PktView) Pkt() (*Pkt, error) {
+	return NewFromBytes(v.b)
+}
+
+// OptionIter iterates over the options of a PktView. Pad, End and option
+// overload are handled by the iterator and never returned.
+//
+//	it := v.Options()
+//	for it.Next() {
+//		fmt.Println(it.Code(), it.Data())
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type OptionIter struct {
+	full []byte
+	// b is the region being parsed, at offset base of the packet
+	b    []byte
+	base int
+	pos  int
+
+	region   int
+	overload uint8
+
+	code OptionCode
+	data []byte
+	err  error
+}
+
+// Next advances to the next option and reports whether there is one
+func (it *OptionIter) Next() bool {
+	for it.err == nil {
+		if it.pos >= len(it.b) {
+			if !it.nextRegion() {
+				return false
+			}
+			continue
+		}
+		b, i := it.b, it.pos
+		code := OptionCode(b[i])
+		switch code {
+		case OptionPad:
+			it.pos++
+			continue
+		case OptionEnd:
+			it.pos = len(b)
+			continue
+		}
+		if i+1 >= len(b) {
+			it.err = parseErr(it.base+i, ErrTruncated)
+			return false
+		}
+		end := i + 2 + int(b[i+1])
+		if end > len(b) {
+			it.err = parseErr(it.base+i+1, ErrBadOptionLength)
+			return false
+		}
+		it.pos = end
+		if code == OptionOverload && it.region == regionOptions && end-i == 3 {
+			it.overload = b[i+2]
+			continue
+		}
+		it.code = code
+		it.data = b[i+2 : end : end]
+		return true
+	}
+	return false
+}
+
+// Regions of the packet holding options, in the order they are parsed
+const (
+	regionOptions = iota
+	regionFile
+	regionSName
+	regionDone
+)
+
+// nextRegion moves to the file and then the sname field, if overloaded
+func (it *OptionIter) nextRegion() bool {
+	for it.region < regionDone {
+		it.region++
+		switch it.region {
+		case regionFile:
+			if it.overload&OverloadFile != 0 {
+				it.b, it.base, it.pos = it.full[fileOffset:cookieOffset], fileOffset, 0
+				return true
+			}
+		case regionSName:
+			if it.overload&OverloadSName != 0 {
+				it.b, it.base, it.pos = it.full[snameOffset:fileOffset], snameOffset, 0
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Code returns the code of the current option
+func (it *OptionIter) Code() OptionCode { return it.code }
+
+// Data returns the data of the current option. It shares the packet buffer.
+func (it *OptionIter) Data() []byte { return it.data }
+
+// Err returns the error that stopped the iteration, if any
+func (it *OptionIter) Err() error { return it.err }
@@ -0,0 +1,75 @@
+package pkt
+
+import (
+	"net"
+	"testing"
+)
+
+// benchDiscover returns a typical DHCPDISCOVER as sent by an embedded device
+func benchDiscover(b *testing.B) []byte {
+	p := &Pkt{
+		Header: Header{
+			OpCode: OpCodeBootRequest,
+			HType:  HTypeEthernet,
+			XID:    0x3903f326,
+			Flags:  FlagBroadcast,
+			Cookie: [4]byte(dhcpMagicCookie),
+		},
+	}
+	p.SetCHAddr(net.HardwareAddr{0x00, 0x0b, 0x82, 0x01, 0xfc, 0x42})
+	p.Options.Add(NewOptionMessageType(MessageTypeDiscover))
+	p.Options.Add(NewOption(OptionClientIdentifier, []byte{1, 0x00, 0x0b, 0x82, 0x01, 0xfc, 0x42}))
+	p.Options.Add(NewOption(OptionRequestedIPAddress, []byte{192, 168, 0, 10}))
+	p.Options.Add(NewOption(OptionMaxDHCPMessageSize, []byte{0x05, 0xdc}))
+	p.Options.Add(NewOption(OptionVendorClassIdentifier, []byte("udhcp 1.36.1")))
+	p.Options.Add(NewOption(OptionHostName, []byte("plc-rack-3")))
+	p.Options.Add(NewOption(OptionParameterRequestList, []byte{1, 3, 6, 12, 15, 28, 42, 43, 121}))
+	p.Options.Add(NewOptionEnd())
+	buf, err := p.MarshalBinary()
+	if err != nil {
+		b.Fatal(err)
+	}
+	return buf
+}
+
+func BenchmarkUnmarshalBinary(b *testing.B) {
+	buf := benchDiscover(b)
+	b.ReportAllocs()
+	for range b.N {
+		var p Pkt
+		if err := p.UnmarshalBinary(buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkViewMessageType(b *testing.B) {
+	buf := benchDiscover(b)
+	b.ReportAllocs()
+	for range b.N {
+		v, err := NewView(buf)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if v.MessageType() != MessageTypeDiscover {
+			b.Fatal("wrong message type")
+		}
+	}
+}
+
+func BenchmarkViewOptions(b *testing.B) {
+	buf := benchDiscover(b)
+	b.ReportAllocs()
+	for range b.N {
+		v, err := NewView(buf)
+		if err != nil {
+			b.Fatal(err)
+		}
+		it := v.Options()
+		for it.Next() {
+		}
+		if it.Err() != nil {
+			b.Fatal(it.Err())
+		}
+	}
+}
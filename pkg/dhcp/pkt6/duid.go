@@ -0,0 +1,69 @@
+package pkt6
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+)
+
+// DUID is a DHCP unique identifier (RFC 8415 section 11)
+type DUID []byte
+
+// DUID types
+const (
+	DUIDTypeLLT  = 1
+	DUIDTypeEN   = 2
+	DUIDTypeLL   = 3
+	DUIDTypeUUID = 4
+)
+
+// duidEpoch is the base of the DUID-LLT time field
+var duidEpoch = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// NewDUIDLL creates a DUID from a link layer address
+func NewDUIDLL(htype uint16, addr net.HardwareAddr) DUID {
+	d := make(DUID, 4, 4+len(addr))
+	binary.BigEndian.PutUint16(d[0:], DUIDTypeLL)
+	binary.BigEndian.PutUint16(d[2:], htype)
+	return append(d, addr...)
+}
+
+// NewDUIDLLT creates a DUID from a link layer address and a time
+func NewDUIDLLT(htype uint16, t time.Time, addr net.HardwareAddr) DUID {
+	d := make(DUID, 8, 8+len(addr))
+	binary.BigEndian.PutUint16(d[0:], DUIDTypeLLT)
+	binary.BigEndian.PutUint16(d[2:], htype)
+	binary.BigEndian.PutUint32(d[4:], uint32(t.Sub(duidEpoch)/time.Second))
+	return append(d, addr...)
+}
+
+// Type returns the DUID type, or 0 if d is too short
+func (d DUID) Type() uint16 {
+	if len(d) < 2 {
+		return 0
+	}
+	return binary.BigEndian.Uint16(d)
+}
+
+// HardwareAddr returns the link layer address of a DUID-LL or DUID-LLT
+func (d DUID) HardwareAddr() (net.HardwareAddr, bool) {
+	switch d.Type() {
+	case DUIDTypeLLT:
+		if len(d) > 8 {
+			return net.HardwareAddr(d[8:]), true
+		}
+	case DUIDTypeLL:
+		if len(d) > 4 {
+			return net.HardwareAddr(d[4:]), true
+		}
+	}
+	return nil, false
+}
+
+func (d DUID) String() string {
+	if addr, ok := d.HardwareAddr(); ok {
+		return fmt.Sprintf("duid %d %v", d.Type(), addr)
+	}
+	return fmt.Sprintf("duid %x", []byte(d))
+}
@@ -0,0 +1,105 @@
+package pkt6
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+)
+
+// seeds returns encoded messages covering each message format and the IA
+// options
+func seeds(f *testing.F) [][]byte {
+	solicit := &Pkt{Type: MessageTypeSolicit, XID: 0x5a1b2c}
+	solicit.Options.Add(NewOptionClientID(clientDUID))
+	solicit.Options.Add(NewOptionElapsedTime(0))
+	solicit.Options.Add(NewOptionORO([]OptionCode{OptionDNSServers}))
+
+	addr, err := NewOptionIAAddr(IAAddr{Addr: net.ParseIP("2001:db8::10"), ValidLifetime: time.Hour})
+	if err != nil {
+		f.Fatal(err)
+	}
+	na := IA{IAID: 1}
+	na.Options.Add(addr)
+	_, prefix, _ := net.ParseCIDR("2001:db8:1200::/40")
+	pfx, err := NewOptionIAPrefix(IAPrefix{Prefix: *prefix, ValidLifetime: time.Hour})
+	if err != nil {
+		f.Fatal(err)
+	}
+	pd := IA{IAID: 2}
+	pd.Options.Add(pfx)
+
+	iana, err := NewOptionIANA(na)
+	if err != nil {
+		f.Fatal(err)
+	}
+	iapd, err := NewOptionIAPD(pd)
+	if err != nil {
+		f.Fatal(err)
+	}
+	reply := &Pkt{Type: MessageTypeReply, XID: 0x5a1b2c}
+	reply.Options.Add(NewOptionServerID(serverDUID))
+	reply.Options.Add(iana)
+	reply.Options.Add(iapd)
+
+	link, peer := net.ParseIP("2001:db8::1"), net.ParseIP("fe80::1")
+	relay, err := NewRelay(MessageTypeRelayForw, 0, link, peer, solicit)
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	var out [][]byte
+	for _, p := range []*Pkt{solicit, reply, relay} {
+		b, err := p.MarshalBinary()
+		if err != nil {
+			f.Fatal(err)
+		}
+		out = append(out, b)
+	}
+	return out
+}
+
+func FuzzUnmarshalBinary(f *testing.F) {
+	for _, b := range seeds(f) {
+		f.Add(b)
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		p, err := NewFromBytes(b)
+		if err != nil {
+			return
+		}
+
+		// everything that reads a decoded message must cope with any input
+		_, _ = p.Options.IANAs()
+		_, _ = p.Options.IAPDs()
+		_, _ = p.Options.ORO()
+		_, _ = p.Options.ElapsedTime()
+		_, _, _ = p.Options.StatusCode()
+		if duid, ok := p.Options.ClientID(); ok {
+			_ = duid.String()
+		}
+		if p.Type.IsRelay() {
+			_, _ = p.RelayMessage()
+		}
+
+		// decode, encode, decode must be stable
+		enc, err := p.MarshalBinary()
+		if err != nil {
+			t.Fatalf("failed to encode decoded message: %v", err)
+		}
+		if !bytes.Equal(enc, b) {
+			t.Fatalf("encoding changed the message:\n% x\n% x", b, enc)
+		}
+		q, err := NewFromBytes(enc)
+		if err != nil {
+			t.Fatalf("failed to decode encoded message: %v\n% x", err, enc)
+		}
+		enc2, err := q.MarshalBinary()
+		if err != nil {
+			t.Fatalf("failed to encode again: %v", err)
+		}
+		if !bytes.Equal(enc, enc2) {
+			t.Fatalf("encoding not stable:\n% x\n% x", enc, enc2)
+		}
+	})
+}
@@ -0,0 +1,261 @@
+package pkt6
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/jon-ski/dhcpset/pkg/dhcp/pkt"
+)
+
+// IA is an identity association for non-temporary addresses (IA_NA) or
+// for prefix delegation (IA_PD). Both share the same layout.
+type IA struct {
+	IAID    uint32
+	T1      time.Duration
+	T2      time.Duration
+	Options Options
+}
+
+// iaHeaderLen is the length of the IAID, T1 and T2 fields
+const iaHeaderLen = 12
+
+// ParseIA decodes the data of an IA_NA or IA_PD option
+func ParseIA(data []byte) (IA, error) {
+	var ia IA
+	if len(data) < iaHeaderLen {
+		return ia, &pkt.ParseError{Offset: len(data), Err: pkt.ErrTruncated}
+	}
+	ia.IAID = binary.BigEndian.Uint32(data[0:])
+	ia.T1 = seconds(data[4:])
+	ia.T2 = seconds(data[8:])
+	err := ia.Options.decode(data[iaHeaderLen:], iaHeaderLen)
+	return ia, err
+}
+
+func (ia IA) MarshalBinary() ([]byte, error) {
+	opts, err := ia.Options.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, iaHeaderLen, iaHeaderLen+len(opts))
+	binary.BigEndian.PutUint32(buf[0:], ia.IAID)
+	putSeconds(buf[4:], ia.T1)
+	putSeconds(buf[8:], ia.T2)
+	return append(buf, opts...), nil
+}
+
+// Addresses returns the IAADDR options of an IA_NA
+func (ia IA) Addresses() ([]IAAddr, error) {
+	var addrs []IAAddr
+	for _, opt := range ia.Options.GetAll(OptionIAAddr) {
+		addr, err := ParseIAAddr(opt.Data)
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
+}
+
+// Prefixes returns the IAPREFIX options of an IA_PD
+func (ia IA) Prefixes() ([]IAPrefix, error) {
+	var prefixes []IAPrefix
+	for _, opt := range ia.Options.GetAll(OptionIAPrefix) {
+		prefix, err := ParseIAPrefix(opt.Data)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes, nil
+}
+
+// NewOptionIANA creates an IA_NA option
+func NewOptionIANA(ia IA) (Option, error) {
+	data, err := ia.MarshalBinary()
+	if err != nil {
+		return Option{}, err
+	}
+	return NewOption(OptionIANA, data), nil
+}
+
+// NewOptionIAPD creates an IA_PD option
+func NewOptionIAPD(ia IA) (Option, error) {
+	data, err := ia.MarshalBinary()
+	if err != nil {
+		return Option{}, err
+	}
+	return NewOption(OptionIAPD, data), nil
+}
+
+// IANAs returns every IA_NA option of the message
+func (o *Options) IANAs() ([]IA, error) {
+	return o.ias(OptionIANA)
+}
+
+// IAPDs returns every IA_PD option of the message
+func (o *Options) IAPDs() ([]IA, error) {
+	return o.ias(OptionIAPD)
+}
+
+func (o *Options) ias(code OptionCode) ([]IA, error) {
+	var ias []IA
+	for _, opt := range o.GetAll(code) {
+		ia, err := ParseIA(opt.Data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %v: %w", code, err)
+		}
+		ias = append(ias, ia)
+	}
+	return ias, nil
+}
+
+// IAAddr is an address assigned in an IA_NA (RFC 8415 section 21.6)
+type IAAddr struct {
+	Addr              net.IP
+	PreferredLifetime time.Duration
+	ValidLifetime     time.Duration
+	Options           Options
+}
+
+// iaAddrHeaderLen is the length of the address and lifetime fields
+const iaAddrHeaderLen = 24
+
+// ParseIAAddr decodes the data of an IAADDR option
+func ParseIAAddr(data []byte) (IAAddr, error) {
+	var a IAAddr
+	if len(data) < iaAddrHeaderLen {
+		return a, &pkt.ParseError{Offset: len(data), Err: pkt.ErrTruncated}
+	}
+	a.Addr = net.IP(append([]byte(nil), data[:net.IPv6len]...))
+	a.PreferredLifetime = seconds(data[16:])
+	a.ValidLifetime = seconds(data[20:])
+	err := a.Options.decode(data[iaAddrHeaderLen:], iaAddrHeaderLen)
+	return a, err
+}
+
+func (a IAAddr) MarshalBinary() ([]byte, error) {
+	addr := a.Addr.To16()
+	if addr == nil {
+		return nil, fmt.Errorf("invalid address %v", a.Addr)
+	}
+	opts, err := a.Options.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, iaAddrHeaderLen, iaAddrHeaderLen+len(opts))
+	copy(buf, addr)
+	putSeconds(buf[16:], a.PreferredLifetime)
+	putSeconds(buf[20:], a.ValidLifetime)
+	return append(buf, opts...), nil
+}
+
+// NewOptionIAAddr creates an IAADDR option
+func NewOptionIAAddr(a IAAddr) (Option, error) {
+	data, err := a.MarshalBinary()
+	if err != nil {
+		return Option{}, err
+	}
+	return NewOption(OptionIAAddr, data), nil
+}
+
+// IAPrefix is a prefix delegated in an IA_PD (RFC 8415 section 21.22)
+type IAPrefix struct {
+	PreferredLifetime time.Duration
+	ValidLifetime     time.Duration
+	Prefix            net.IPNet
+	Options           Options
+}
+
+// iaPrefixHeaderLen is the length of the lifetime, length and prefix fields
+const iaPrefixHeaderLen = 25
+
+// ParseIAPrefix decodes the data of an IAPREFIX option
+func ParseIAPrefix(data []byte) (IAPrefix, error) {
+	var p IAPrefix
+	if len(data) < iaPrefixHeaderLen {
+		return p, &pkt.ParseError{Offset: len(data), Err: pkt.ErrTruncated}
+	}
+	p.PreferredLifetime = seconds(data[0:])
+	p.ValidLifetime = seconds(data[4:])
+	if data[8] > 128 {
+		return p, &pkt.ParseError{Offset: 8, Err: pkt.ErrBadOptionLength}
+	}
+	p.Prefix = net.IPNet{
+		IP:   net.IP(append([]byte(nil), data[9:25]...)),
+		Mask: net.CIDRMask(int(data[8]), 128),
+	}
+	err := p.Options.decode(data[iaPrefixHeaderLen:], iaPrefixHeaderLen)
+	return p, err
+}
+
+func (p IAPrefix) MarshalBinary() ([]byte, error) {
+	prefix := p.Prefix.IP.To16()
+	ones, bits := p.Prefix.Mask.Size()
+	if prefix == nil || bits != 128 {
+		return nil, fmt.Errorf("invalid prefix %v", &p.Prefix)
+	}
+	opts, err := p.Options.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, iaPrefixHeaderLen, iaPrefixHeaderLen+len(opts))
+	putSeconds(buf[0:], p.PreferredLifetime)
+	putSeconds(buf[4:], p.ValidLifetime)
+	buf[8] = byte(ones)
+	copy(buf[9:], prefix)
+	return append(buf, opts...), nil
+}
+
+// NewOptionIAPrefix creates an IAPREFIX option
+func NewOptionIAPrefix(p IAPrefix) (Option, error) {
+	data, err := p.MarshalBinary()
+	if err != nil {
+		return Option{}, err
+	}
+	return NewOption(OptionIAPrefix, data), nil
+}
+
+// StatusCode values (RFC 8415 section 21.13)
+const (
+	StatusSuccess       = 0
+	StatusUnspecFail    = 1
+	StatusNoAddrsAvail  = 2
+	StatusNoBinding     = 3
+	StatusNotOnLink     = 4
+	StatusUseMulticast  = 5
+	StatusNoPrefixAvail = 6
+)
+
+// NewOptionStatusCode creates a status code option
+func NewOptionStatusCode(code uint16, msg string) Option {
+	data := make([]byte, 2, 2+len(msg))
+	binary.BigEndian.PutUint16(data, code)
+	return NewOption(OptionStatusCode, append(data, msg...))
+}
+
+// StatusCode returns the status code option
+func (o *Options) StatusCode() (uint16, string, bool) {
+	opt, ok := o.Get(OptionStatusCode)
+	if !ok || len(opt.Data) < 2 {
+		return 0, "", false
+	}
+	return binary.BigEndian.Uint16(opt.Data), string(opt.Data[2:]), true
+}
+
+// infinity is the lifetime that never expires
+const infinity = 0xffffffff
+
+func seconds(b []byte) time.Duration {
+	return time.Duration(binary.BigEndian.Uint32(b)) * time.Second
+}
+
+func putSeconds(b []byte, d time.Duration) {
+	s := d / time.Second
+	if s > infinity {
+		s = infinity
+	}
+	binary.BigEndian.PutUint32(b, uint32(s))
+}
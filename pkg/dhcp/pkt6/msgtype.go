@@ -0,0 +1,50 @@
+package pkt6
+
+import "fmt"
+
+// MessageType is the DHCPv6 msg-type field (RFC 8415 section 7.3)
+type MessageType uint8
+
+const (
+	MessageTypeSolicit            MessageType = 1
+	MessageTypeAdvertise          MessageType = 2
+	MessageTypeRequest            MessageType = 3
+	MessageTypeConfirm            MessageType = 4
+	MessageTypeRenew              MessageType = 5
+	MessageTypeRebind             MessageType = 6
+	MessageTypeReply              MessageType = 7
+	MessageTypeRelease            MessageType = 8
+	MessageTypeDecline            MessageType = 9
+	MessageTypeReconfigure        MessageType = 10
+	MessageTypeInformationRequest MessageType = 11
+	MessageTypeRelayForw          MessageType = 12
+	MessageTypeRelayRepl          MessageType = 13
+)
+
+var messageTypeNames = map[MessageType]string{
+	MessageTypeSolicit:            "SOLICIT",
+	MessageTypeAdvertise:          "ADVERTISE",
+	MessageTypeRequest:            "REQUEST",
+	MessageTypeConfirm:            "CONFIRM",
+	MessageTypeRenew:              "RENEW",
+	MessageTypeRebind:             "REBIND",
+	MessageTypeReply:              "REPLY",
+	MessageTypeRelease:            "RELEASE",
+	MessageTypeDecline:            "DECLINE",
+	MessageTypeReconfigure:        "RECONFIGURE",
+	MessageTypeInformationRequest: "INFORMATION-REQUEST",
+	MessageTypeRelayForw:          "RELAY-FORW",
+	MessageTypeRelayRepl:          "RELAY-REPL",
+}
+
+func (t MessageType) String() string {
+	if name, ok := messageTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("MessageType(%d)", uint8(t))
+}
+
+// IsRelay reports whether t uses the relay agent message format
+func (t MessageType) IsRelay() bool {
+	return t == MessageTypeRelayForw || t == MessageTypeRelayRepl
+}
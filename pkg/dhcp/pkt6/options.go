@@ -0,0 +1,261 @@
+package pkt6
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/jon-ski/dhcpset/pkg/dhcp/pkt"
+)
+
+// OptionCode is a DHCPv6 option code (RFC 8415 section 21)
+type OptionCode uint16
+
+const (
+	OptionClientID     OptionCode = 1
+	OptionServerID     OptionCode = 2
+	OptionIANA         OptionCode = 3
+	OptionIATA         OptionCode = 4
+	OptionIAAddr       OptionCode = 5
+	OptionORO          OptionCode = 6
+	OptionPreference   OptionCode = 7
+	OptionElapsedTime  OptionCode = 8
+	OptionRelayMsg     OptionCode = 9
+	OptionAuth         OptionCode = 11
+	OptionUnicast      OptionCode = 12
+	OptionStatusCode   OptionCode = 13
+	OptionRapidCommit  OptionCode = 14
+	OptionUserClass    OptionCode = 15
+	OptionVendorClass  OptionCode = 16
+	OptionVendorOpts   OptionCode = 17
+	OptionInterfaceID  OptionCode = 18
+	OptionReconfMsg    OptionCode = 19
+	OptionReconfAccept OptionCode = 20
+	OptionDNSServers   OptionCode = 23
+	OptionDomainList   OptionCode = 24
+	OptionIAPD         OptionCode = 25
+	OptionIAPrefix     OptionCode = 26
+)
+
+var optionNames = map[OptionCode]string{
+	OptionClientID:     "Client Identifier",
+	OptionServerID:     "Server Identifier",
+	OptionIANA:         "IA_NA",
+	OptionIATA:         "IA_TA",
+	OptionIAAddr:       "IA Address",
+	OptionORO:          "Option Request",
+	OptionPreference:   "Preference",
+	OptionElapsedTime:  "Elapsed Time",
+	OptionRelayMsg:     "Relay Message",
+	OptionAuth:         "Authentication",
+	OptionUnicast:      "Server Unicast",
+	OptionStatusCode:   "Status Code",
+	OptionRapidCommit:  "Rapid Commit",
+	OptionUserClass:    "User Class",
+	OptionVendorClass:  "Vendor Class",
+	OptionVendorOpts:   "Vendor-specific Information",
+	OptionInterfaceID:  "Interface-Id",
+	OptionReconfMsg:    "Reconfigure Message",
+	OptionReconfAccept: "Reconfigure Accept",
+	OptionDNSServers:   "DNS Recursive Name Server",
+	OptionDomainList:   "Domain Search List",
+	OptionIAPD:         "IA_PD",
+	OptionIAPrefix:     "IA Prefix",
+}
+
+func (c OptionCode) String() string {
+	if name, ok := optionNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("Option %d", uint16(c))
+}
+
+// Option is a single DHCPv6 option. The length on the wire is derived from Data.
+type Option struct {
+	Code OptionCode
+	Data []byte
+}
+
+// NewOption creates an option with the given data
+func NewOption(code OptionCode, data []byte) Option {
+	return Option{
+		Code: code,
+		Data: data,
+	}
+}
+
+// maxOptionLen is the most data a single option can carry
+const maxOptionLen = 0xffff
+
+func (o *Option) MarshalBinary() ([]byte, error) {
+	if len(o.Data) > maxOptionLen {
+		return nil, fmt.Errorf("%w: %v", pkt.ErrBadOptionLength, o.Code)
+	}
+	buf := make([]byte, 4+len(o.Data))
+	binary.BigEndian.PutUint16(buf[0:], uint16(o.Code))
+	binary.BigEndian.PutUint16(buf[2:], uint16(len(o.Data)))
+	copy(buf[4:], o.Data)
+	return buf, nil
+}
+
+// Options is a list of DHCPv6 options. Unlike DHCPv4 there is no Pad or End.
+type Options struct {
+	Options []Option
+}
+
+func (o *Options) MarshalBinary() ([]byte, error) {
+	var buf []byte
+	for _, opt := range o.Options {
+		b, err := opt.MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal option: %w", err)
+		}
+		buf = append(buf, b...)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary parses a list of options
+func (o *Options) UnmarshalBinary(b []byte) error {
+	o.Options = nil
+	return o.decode(b, 0)
+}
+
+// decode parses the options in b. base is the offset of b within the
+// message and is only used for error reporting.
+func (o *Options) decode(b []byte, base int) error {
+	for i := 0; i < len(b); {
+		if i+4 > len(b) {
+			return &pkt.ParseError{Offset: base + i, Err: pkt.ErrTruncated}
+		}
+		code := OptionCode(binary.BigEndian.Uint16(b[i:]))
+		n := int(binary.BigEndian.Uint16(b[i+2:]))
+		if i+4+n > len(b) {
+			return &pkt.ParseError{Offset: base + i + 2, Err: pkt.ErrBadOptionLength}
+		}
+		o.Options = append(o.Options, Option{
+			Code: code,
+			Data: append([]byte(nil), b[i+4:i+4+n]...),
+		})
+		i += 4 + n
+	}
+	return nil
+}
+
+func (o *Options) Add(opt Option) {
+	o.Options = append(o.Options, opt)
+}
+
+// Get returns the first option with the given code
+func (o *Options) Get(code OptionCode) (Option, bool) {
+	for _, opt := range o.Options {
+		if opt.Code == code {
+			return opt, true
+		}
+	}
+	return Option{}, false
+}
+
+// GetAll returns every option with the given code. IA options may repeat.
+func (o *Options) GetAll(code OptionCode) []Option {
+	var opts []Option
+	for _, opt := range o.Options {
+		if opt.Code == code {
+			opts = append(opts, opt)
+		}
+	}
+	return opts
+}
+
+// Has reports whether an option with the given code is present
+func (o *Options) Has(code OptionCode) bool {
+	_, ok := o.Get(code)
+	return ok
+}
+
+// Set replaces the option with the same code, or adds it
+func (o *Options) Set(opt Option) {
+	for i := range o.Options {
+		if o.Options[i].Code == opt.Code {
+			o.Options[i] = opt
+			return
+		}
+	}
+	o.Options = append(o.Options, opt)
+}
+
+// Delete removes every option with the given code
+func (o *Options) Delete(code OptionCode) {
+	opts := o.Options[:0]
+	for _, opt := range o.Options {
+		if opt.Code != code {
+			opts = append(opts, opt)
+		}
+	}
+	o.Options = opts
+}
+
+// ClientID returns the client DUID
+func (o *Options) ClientID() (DUID, bool) {
+	opt, ok := o.Get(OptionClientID)
+	return DUID(opt.Data), ok
+}
+
+// ServerID returns the server DUID
+func (o *Options) ServerID() (DUID, bool) {
+	opt, ok := o.Get(OptionServerID)
+	return DUID(opt.Data), ok
+}
+
+// NewOptionClientID creates a client identifier option
+func NewOptionClientID(duid DUID) Option {
+	return NewOption(OptionClientID, []byte(duid))
+}
+
+// NewOptionServerID creates a server identifier option
+func NewOptionServerID(duid DUID) Option {
+	return NewOption(OptionServerID, []byte(duid))
+}
+
+// ORO returns the option request option
+func (o *Options) ORO() ([]OptionCode, bool) {
+	opt, ok := o.Get(OptionORO)
+	if !ok || len(opt.Data)%2 != 0 {
+		return nil, false
+	}
+	codes := make([]OptionCode, len(opt.Data)/2)
+	for i := range codes {
+		codes[i] = OptionCode(binary.BigEndian.Uint16(opt.Data[2*i:]))
+	}
+	return codes, true
+}
+
+// NewOptionORO creates an option request option
+func NewOptionORO(codes []OptionCode) Option {
+	data := make([]byte, 2*len(codes))
+	for i, code := range codes {
+		binary.BigEndian.PutUint16(data[2*i:], uint16(code))
+	}
+	return NewOption(OptionORO, data)
+}
+
+// ElapsedTime returns the elapsed time option
+func (o *Options) ElapsedTime() (time.Duration, bool) {
+	opt, ok := o.Get(OptionElapsedTime)
+	if !ok || len(opt.Data) != 2 {
+		return 0, false
+	}
+	// elapsed time is in hundredths of a second
+	return time.Duration(binary.BigEndian.Uint16(opt.Data)) * 10 * time.Millisecond, true
+}
+
+// NewOptionElapsedTime creates an elapsed time option
+func NewOptionElapsedTime(d time.Duration) Option {
+	cs := d / (10 * time.Millisecond)
+	if cs > 0xffff {
+		cs = 0xffff
+	}
+	data := make([]byte, 2)
+	binary.BigEndian.PutUint16(data, uint16(cs))
+	return NewOption(OptionElapsedTime, data)
+}
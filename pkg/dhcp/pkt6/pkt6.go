@@ -0,0 +1,127 @@
+package pkt6
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/jon-ski/dhcpset/pkg/dhcp/pkt"
+)
+
+// Header lengths of the two message formats
+const (
+	headerLen      = 4
+	relayHeaderLen = 34
+)
+
+// Pkt is a DHCPv6 message. Client/server messages use XID, relay
+// messages use HopCount, LinkAddr and PeerAddr.
+type Pkt struct {
+	Type MessageType
+	XID  uint32 // 24 bit transaction ID
+
+	HopCount uint8
+	LinkAddr net.IP
+	PeerAddr net.IP
+
+	Options Options
+}
+
+func NewPkt() *Pkt {
+	return &Pkt{}
+}
+
+func NewFromBytes(b []byte) (*Pkt, error) {
+	pkt := NewPkt()
+	err := pkt.UnmarshalBinary(b)
+	if err != nil {
+		return nil, err
+	}
+	return pkt, nil
+}
+
+func (p *Pkt) UnmarshalBinary(b []byte) error {
+	if len(b) < headerLen {
+		return &pkt.ParseError{Offset: len(b), Err: pkt.ErrTruncated}
+	}
+	p.Type = MessageType(b[0])
+	p.Options.Options = nil
+
+	if !p.Type.IsRelay() {
+		p.XID = uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+		err := p.Options.decode(b[headerLen:], headerLen)
+		if err != nil {
+			return fmt.Errorf("failed to decode options: %w", err)
+		}
+		return nil
+	}
+
+	if len(b) < relayHeaderLen {
+		return &pkt.ParseError{Offset: len(b), Err: pkt.ErrTruncated}
+	}
+	p.HopCount = b[1]
+	p.LinkAddr = net.IP(append([]byte(nil), b[2:18]...))
+	p.PeerAddr = net.IP(append([]byte(nil), b[18:34]...))
+	err := p.Options.decode(b[relayHeaderLen:], relayHeaderLen)
+	if err != nil {
+		return fmt.Errorf("failed to decode options: %w", err)
+	}
+	return nil
+}
+
+func (p *Pkt) MarshalBinary() ([]byte, error) {
+	var buf []byte
+	if !p.Type.IsRelay() {
+		if p.XID > 0xffffff {
+			return nil, fmt.Errorf("transaction ID %x does not fit in 24 bits", p.XID)
+		}
+		buf = []byte{byte(p.Type), byte(p.XID >> 16), byte(p.XID >> 8), byte(p.XID)}
+	} else {
+		link := p.LinkAddr.To16()
+		peer := p.PeerAddr.To16()
+		if link == nil {
+			link = net.IPv6unspecified
+		}
+		if peer == nil {
+			peer = net.IPv6unspecified
+		}
+		buf = make([]byte, 0, relayHeaderLen)
+		buf = append(buf, byte(p.Type), p.HopCount)
+		buf = append(buf, link...)
+		buf = append(buf, peer...)
+	}
+
+	options, err := p.Options.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal options: %w", err)
+	}
+	return append(buf, options...), nil
+}
+
+// RelayMessage decodes the message carried in the relay message option
+// of a Relay-Forw or Relay-Repl message
+func (p *Pkt) RelayMessage() (*Pkt, error) {
+	opt, ok := p.Options.Get(OptionRelayMsg)
+	if !ok {
+		return nil, fmt.Errorf("%v has no relay message option", p.Type)
+	}
+	return NewFromBytes(opt.Data)
+}
+
+// NewRelay wraps inner in a relay message of type t
+func NewRelay(t MessageType, hops uint8, link, peer net.IP, inner *Pkt) (*Pkt, error) {
+	if !t.IsRelay() {
+		return nil, fmt.Errorf("%v is not a relay message type", t)
+	}
+	data, err := inner.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal relayed message: %w", err)
+	}
+	p := &Pkt{
+		Type:     t,
+		HopCount: hops,
+		LinkAddr: link,
+		PeerAddr: peer,
+	}
+	p.Options.Add(NewOption(OptionRelayMsg, data))
+	return p, nil
+}
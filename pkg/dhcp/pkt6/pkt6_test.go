@@ -0,0 +1,242 @@
+package pkt6
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+)
+
+var (
+	clientDUID = NewDUIDLL(1, net.HardwareAddr{0x00, 0x0b, 0x82, 0x01, 0xfc, 0x42})
+	serverDUID = NewDUIDLLT(1, time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC),
+		net.HardwareAddr{0x00, 0x1d, 0x9c, 0xc0, 0x01, 0x02})
+)
+
+// roundTrip encodes p, decodes the result and checks that encoding the
+// decoded message gives the same bytes
+func roundTrip(t *testing.T, p *Pkt) *Pkt {
+	t.Helper()
+	b, err := p.MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	q, err := NewFromBytes(b)
+	if err != nil {
+		t.Fatalf("failed to unmarshal: %v\n% x", err, b)
+	}
+	b2, err := q.MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to marshal again: %v", err)
+	}
+	if !bytes.Equal(b, b2) {
+		t.Fatalf("encoding not stable:\n% x\n% x", b, b2)
+	}
+	if q.Type != p.Type {
+		t.Fatalf("got type %v, want %v", q.Type, p.Type)
+	}
+	return q
+}
+
+// mustOption returns the option created by a constructor that can fail
+func mustOption(t *testing.T) func(Option, error) Option {
+	return func(opt Option, err error) Option {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("failed to create option: %v", err)
+		}
+		return opt
+	}
+}
+
+func TestSolicitAdvertiseRoundTrip(t *testing.T) {
+	solicit := &Pkt{Type: MessageTypeSolicit, XID: 0x5a1b2c}
+	solicit.Options.Add(NewOptionClientID(clientDUID))
+	solicit.Options.Add(NewOptionElapsedTime(1500 * time.Millisecond))
+	solicit.Options.Add(NewOptionORO([]OptionCode{OptionDNSServers, OptionDomainList}))
+	solicit.Options.Add(NewOption(OptionRapidCommit, nil))
+
+	got := roundTrip(t, solicit)
+	if got.XID != solicit.XID {
+		t.Fatalf("got XID %06x, want %06x", got.XID, solicit.XID)
+	}
+	duid, ok := got.Options.ClientID()
+	if !ok || !bytes.Equal(duid, clientDUID) {
+		t.Fatalf("got client ID %v, want %v", duid, clientDUID)
+	}
+	if addr, _ := duid.HardwareAddr(); addr.String() != "00:0b:82:01:fc:42" {
+		t.Fatalf("got hardware address %v", addr)
+	}
+	if elapsed, ok := got.Options.ElapsedTime(); !ok || elapsed != 1500*time.Millisecond {
+		t.Fatalf("got elapsed time %v", elapsed)
+	}
+	oro, ok := got.Options.ORO()
+	if !ok || len(oro) != 2 || oro[0] != OptionDNSServers || oro[1] != OptionDomainList {
+		t.Fatalf("got ORO %v", oro)
+	}
+	if !got.Options.Has(OptionRapidCommit) {
+		t.Fatal("rapid commit missing")
+	}
+
+	advertise := &Pkt{Type: MessageTypeAdvertise, XID: solicit.XID}
+	advertise.Options.Add(NewOptionClientID(clientDUID))
+	advertise.Options.Add(NewOptionServerID(serverDUID))
+	advertise.Options.Add(NewOption(OptionPreference, []byte{255}))
+	advertise.Options.Add(NewOptionStatusCode(StatusSuccess, "all good"))
+
+	got = roundTrip(t, advertise)
+	duid, ok = got.Options.ServerID()
+	if !ok || !bytes.Equal(duid, serverDUID) || duid.Type() != DUIDTypeLLT {
+		t.Fatalf("got server ID %v, want %v", duid, serverDUID)
+	}
+	code, msg, ok := got.Options.StatusCode()
+	if !ok || code != StatusSuccess || msg != "all good" {
+		t.Fatalf("got status %d %q", code, msg)
+	}
+}
+
+func TestIANARoundTrip(t *testing.T) {
+	addr := IAAddr{
+		Addr:              net.ParseIP("2001:db8::10"),
+		PreferredLifetime: time.Hour,
+		ValidLifetime:     2 * time.Hour,
+	}
+	addr.Options.Add(NewOptionStatusCode(StatusSuccess, ""))
+	ia := IA{IAID: 0x0b8201fc, T1: 30 * time.Minute, T2: 48 * time.Minute}
+	ia.Options.Add(mustOption(t)(NewOptionIAAddr(addr)))
+
+	reply := &Pkt{Type: MessageTypeReply, XID: 0x000102}
+	reply.Options.Add(NewOptionServerID(serverDUID))
+	reply.Options.Add(mustOption(t)(NewOptionIANA(ia)))
+
+	got := roundTrip(t, reply)
+	ias, err := got.Options.IANAs()
+	if err != nil {
+		t.Fatalf("failed to decode IA_NA: %v", err)
+	}
+	if len(ias) != 1 {
+		t.Fatalf("got %d IA_NAs, want 1", len(ias))
+	}
+	if ias[0].IAID != ia.IAID || ias[0].T1 != ia.T1 || ias[0].T2 != ia.T2 {
+		t.Fatalf("got IA_NA %+v, want %+v", ias[0], ia)
+	}
+	addrs, err := ias[0].Addresses()
+	if err != nil {
+		t.Fatalf("failed to decode IAADDR: %v", err)
+	}
+	if len(addrs) != 1 {
+		t.Fatalf("got %d addresses, want 1", len(addrs))
+	}
+	a := addrs[0]
+	if !a.Addr.Equal(addr.Addr) || a.PreferredLifetime != addr.PreferredLifetime ||
+		a.ValidLifetime != addr.ValidLifetime {
+		t.Fatalf("got IAADDR %+v, want %+v", a, addr)
+	}
+	if code, _, ok := a.Options.StatusCode(); !ok || code != StatusSuccess {
+		t.Fatalf("IAADDR status code lost")
+	}
+}
+
+func TestIAPDRoundTrip(t *testing.T) {
+	_, prefix, _ := net.ParseCIDR("2001:db8:1200::/40")
+	p := IAPrefix{
+		PreferredLifetime: time.Hour,
+		ValidLifetime:     infinity * time.Second,
+		Prefix:            *prefix,
+	}
+	ia := IA{IAID: 7, T1: time.Hour, T2: 2 * time.Hour}
+	ia.Options.Add(mustOption(t)(NewOptionIAPrefix(p)))
+
+	reply := &Pkt{Type: MessageTypeReply, XID: 0xfffffe}
+	reply.Options.Add(mustOption(t)(NewOptionIAPD(ia)))
+
+	got := roundTrip(t, reply)
+	pds, err := got.Options.IAPDs()
+	if err != nil {
+		t.Fatalf("failed to decode IA_PD: %v", err)
+	}
+	if len(pds) != 1 || pds[0].IAID != ia.IAID {
+		t.Fatalf("got IA_PDs %+v", pds)
+	}
+	prefixes, err := pds[0].Prefixes()
+	if err != nil {
+		t.Fatalf("failed to decode IAPREFIX: %v", err)
+	}
+	if len(prefixes) != 1 {
+		t.Fatalf("got %d prefixes, want 1", len(prefixes))
+	}
+	if prefixes[0].Prefix.String() != prefix.String() {
+		t.Fatalf("got prefix %v, want %v", &prefixes[0].Prefix, prefix)
+	}
+	if prefixes[0].ValidLifetime != p.ValidLifetime {
+		t.Fatalf("got valid lifetime %v, want %v", prefixes[0].ValidLifetime, p.ValidLifetime)
+	}
+}
+
+func TestRelayRoundTrip(t *testing.T) {
+	solicit := &Pkt{Type: MessageTypeSolicit, XID: 0x123456}
+	solicit.Options.Add(NewOptionClientID(clientDUID))
+
+	link := net.ParseIP("2001:db8:0:1::1")
+	peer := net.ParseIP("fe80::20b:82ff:fe01:fc42")
+	forw, err := NewRelay(MessageTypeRelayForw, 1, link, peer, solicit)
+	if err != nil {
+		t.Fatalf("failed to create relay message: %v", err)
+	}
+	forw.Options.Add(NewOption(OptionInterfaceID, []byte("eth0")))
+
+	got := roundTrip(t, forw)
+	if got.HopCount != 1 || !got.LinkAddr.Equal(link) || !got.PeerAddr.Equal(peer) {
+		t.Fatalf("got relay header %d %v %v", got.HopCount, got.LinkAddr, got.PeerAddr)
+	}
+	inner, err := got.RelayMessage()
+	if err != nil {
+		t.Fatalf("failed to decode relayed message: %v", err)
+	}
+	if inner.Type != MessageTypeSolicit || inner.XID != solicit.XID {
+		t.Fatalf("got relayed %v %06x", inner.Type, inner.XID)
+	}
+
+	advertise := &Pkt{Type: MessageTypeAdvertise, XID: solicit.XID}
+	advertise.Options.Add(NewOptionServerID(serverDUID))
+	repl, err := NewRelay(MessageTypeRelayRepl, got.HopCount, got.LinkAddr, got.PeerAddr, advertise)
+	if err != nil {
+		t.Fatalf("failed to create relay reply: %v", err)
+	}
+	repl.Options.Add(NewOption(OptionInterfaceID, []byte("eth0")))
+
+	got = roundTrip(t, repl)
+	inner, err = got.RelayMessage()
+	if err != nil {
+		t.Fatalf("failed to decode relayed reply: %v", err)
+	}
+	if inner.Type != MessageTypeAdvertise || inner.XID != advertise.XID {
+		t.Fatalf("got relayed %v %06x", inner.Type, inner.XID)
+	}
+	if id, ok := got.Options.Get(OptionInterfaceID); !ok || string(id.Data) != "eth0" {
+		t.Fatalf("interface ID lost")
+	}
+
+	if _, err := NewRelay(MessageTypeSolicit, 0, link, peer, solicit); err == nil {
+		t.Fatal("created a relay message of a client message type")
+	}
+}
+
+func TestUnmarshalTruncated(t *testing.T) {
+	tests := []struct {
+		name string
+		b    []byte
+	}{
+		{"short header", []byte{byte(MessageTypeSolicit), 0, 1}},
+		{"short relay header", append([]byte{byte(MessageTypeRelayForw), 0}, make([]byte, 20)...)},
+		{"option past the end", []byte{byte(MessageTypeSolicit), 0, 0, 1, 0, 1, 0, 8, 0xaa}},
+		{"partial option header", []byte{byte(MessageTypeSolicit), 0, 0, 1, 0, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewFromBytes(tt.b); err == nil {
+				t.Fatal("decoded a truncated message")
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package dhcp
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jon-ski/dhcpset/pkg/dhcp/pkt"
+)
+
+// mandatoryOptions are sent in every OFFER and ACK whether or not the
+// client asks for them (RFC 2131 table 3)
+var mandatoryOptions = []pkt.OptionCode{
+	pkt.OptionIPAddressLeaseTime,
+}
+
+// SetOption configures an option to send to clients that request it,
+// replacing any option with the same code
+func (s *Server) SetOption(opt pkt.Option) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.options.Set(opt)
+}
+
+// SetRoutes configures the classless static routes (option 121) to offer
+func (s *Server) SetRoutes(routes []pkt.Route) error {
+	opt, err := pkt.NewOptionClasslessRoutes(routes)
+	if err != nil {
+		return fmt.Errorf("failed to encode routes: %w", err)
+	}
+	s.SetOption(opt)
+	return nil
+}
+
+// SetDomainSearch configures the domain search list (option 119) to offer
+func (s *Server) SetDomainSearch(domains []string) error {
+	opt, err := pkt.NewOptionDomainSearch(domains)
+	if err != nil {
+		return fmt.Errorf("failed to encode domain search list: %w", err)
+	}
+	s.SetOption(opt)
+	return nil
+}
+
+// SetLeaseTime configures the lease time (option 51) sent in every OFFER
+// and ACK. It defaults to pkt.InfiniteLease.
+func (s *Server) SetLeaseTime(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.options.SetDuration(pkt.OptionIPAddressLeaseTime, d)
+}
+
+// SetSubnetMask configures the subnet mask (option 1). It defaults to /24.
+func (s *Server) SetSubnetMask(mask net.IPMask) {
+	s.SetOption(pkt.NewOptionSubnetMask(mask))
+}
+
+// dhcpOnlyOptions make no sense to a BOOTP client, which has no lease
+var dhcpOnlyOptions = []pkt.OptionCode{
+	pkt.OptionIPAddressLeaseTime,
+	pkt.OptionRenewalTime,
+	pkt.OptionRebindingTime,
+}
+
+// vendorRule matches clients by the prefix of their vendor class
+// identifier (option 60)
+type vendorRule struct {
+	prefix string
+	option pkt.Option
+}
+
+// AddVendorOptions sends vendor specific information (option 43) holding
+// subs to clients whose vendor class identifier starts with prefix.
+// Rules are tried in the order they were added and the first match wins.
+func (s *Server) AddVendorOptions(prefix string, subs pkt.Options) error {
+	opt, err := pkt.NewOptionVendorSpecific(subs)
+	if err != nil {
+		return fmt.Errorf("failed to encode vendor options: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.vendorRules = append(s.vendorRules, vendorRule{
+		prefix: prefix,
+		option: opt,
+	})
+	return nil
+}
+
+// matchVendor returns the option 43 for a client's vendor class, if any.
+// s.mu must be held.
+func (s *Server) matchVendor(req *pkt.Pkt) (pkt.Option, bool) {
+	class, ok := req.VendorClass()
+	if !ok {
+		return pkt.Option{}, false
+	}
+	for _, rule := range s.vendorRules {
+		if strings.HasPrefix(class, rule.prefix) {
+			slog.Debug("matched vendor class", "class", class, "prefix", rule.prefix)
+			return rule.option, true
+		}
+	}
+	return pkt.Option{}, false
+}
+
+// addReplyOptions fills in the options of a reply to req after the message
+// type: the server identifier, then the configured options filtered and
+// ordered by the client's parameter request list, then the relay agent
+// information echoed from the request. Vendor specific information for a
+// matching vendor class is always included.
+func (s *Server) addReplyOptions(reply, req *pkt.Pkt) {
+	reply.Options.Add(pkt.NewOptionServerID(s.addr.To4()))
+
+	prl, _ := req.RequestedOptions()
+	s.mu.Lock()
+	available := s.options
+	mandatory := mandatoryOptions
+	if vendor, matched := s.matchVendor(req); matched {
+		available = pkt.Options{Options: slices.Clone(s.options.Options)}
+		available.Set(vendor)
+		mandatory = append(slices.Clone(mandatory), pkt.OptionVendorSpecific)
+	}
+	selected := pkt.SelectOptions(available, prl, mandatory...)
+	s.mu.Unlock()
+	for _, opt := range selected {
+		reply.Options.Add(opt)
+	}
+
+	if relay, ok := req.Options.Get(pkt.OptionRelayAgentInfo); ok {
+		reply.Options.Add(relay)
+	}
+	reply.Options.Add(pkt.NewOptionEnd())
+}
+
+// addBOOTPOptions fills in the RFC 1048 vendor extensions of a reply to a
+// BOOTP client: every configured option except the DHCP lease times, and
+// the relay agent information echoed from the request
+func (s *Server) addBOOTPOptions(reply, req *pkt.Pkt) {
+	s.mu.Lock()
+	for _, opt := range s.options.Options {
+		if !slices.Contains(dhcpOnlyOptions, opt.Type) {
+			reply.Options.Add(opt)
+		}
+	}
+	if vendor, matched := s.matchVendor(req); matched {
+		reply.Options.Add(vendor)
+	}
+	s.mu.Unlock()
+
+	if relay, ok := req.Options.Get(pkt.OptionRelayAgentInfo); ok {
+		reply.Options.Add(relay)
+	}
+	reply.Options.Add(pkt.NewOptionEnd())
+}
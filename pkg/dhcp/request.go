@@ -0,0 +1,120 @@
+package dhcp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+
+	"github.com/jon-ski/dhcpset/pkg/dhcp/pkt"
+)
+
+var (
+	// ErrNak is returned when the client requested an address it cannot
+	// have and was sent a DHCPNAK
+	ErrNak = errors.New("client request refused")
+	// ErrOtherServer is returned when the client accepted another
+	// server's offer
+	ErrOtherServer = errors.New("client chose another server")
+)
+
+// requestVerdict is how the server answers a DHCPREQUEST
+type requestVerdict int
+
+const (
+	requestAccept    requestVerdict = iota // ACK it
+	requestIgnore                          // not from the client, stay silent
+	requestElsewhere                       // the client took another offer
+	requestRefuse                          // NAK it
+)
+
+// checkRequest decides how to answer req, a DHCPREQUEST from the client
+// identified by id and hwAddr, given that ip is the address offered or
+// reserved for it. The reason explains anything but an accept.
+func (s *Server) checkRequest(
+	req *pkt.Pkt, id ClientID, hwAddr net.HardwareAddr, ip net.IP,
+) (requestVerdict, string) {
+	sameHwAddr := len(hwAddr) > 0 && bytes.Equal(hwAddr, ClientHwAddr(req))
+	if ClientIDFromPkt(req) != id && !sameHwAddr {
+		return requestIgnore, fmt.Sprintf("REQUEST from other client %v", ClientIDFromPkt(req))
+	}
+
+	state := req.RequestState()
+	requested, _ := req.Options.GetIP(pkt.OptionRequestedIPAddress)
+	switch state {
+	case pkt.RequestSelecting:
+		serverID, _ := req.Options.GetIP(pkt.OptionServerIdentifier)
+		if !serverID.Equal(s.addr) {
+			return requestElsewhere, fmt.Sprintf("client chose server %v", serverID)
+		}
+	case pkt.RequestRenewing:
+		requested = IPv4(req.Header.CIAddr[:])
+	}
+	if !requested.Equal(ip) {
+		return requestRefuse, fmt.Sprintf("%v requested %v, not %v", state, requested, ip)
+	}
+	return requestAccept, ""
+}
+
+// newNak returns the DHCPNAK refusing req, with reason as the message
+// (option 56) for the client and the relay agent information echoed from
+// the request (RFC 3046 section 2.2)
+func (s *Server) newNak(req *pkt.Pkt, reason string) *pkt.Pkt {
+	reply := pkt.NewReplyTo(req, pkt.MessageTypeNak)
+	reply.Options.Add(pkt.NewOptionServerID(s.addr.To4()))
+	reply.Options.Add(pkt.NewOption(pkt.OptionMessage, []byte(reason)))
+	if relay, ok := req.Options.Get(pkt.OptionRelayAgentInfo); ok {
+		reply.Options.Add(relay)
+	}
+	reply.Options.Add(pkt.NewOptionEnd())
+	return reply
+}
+
+// Nak refuses a client's DHCPREQUEST, telling it to start over
+func (s *Server) Nak(req *pkt.Pkt, reason string) error {
+	p := s.newNak(req, reason)
+	slog.Debug("sending nak", "packet", p, "reason", reason)
+	return s.Write(p)
+}
+
+// answerRequest answers a DHCPREQUEST outside a transaction run by
+// Assign: a client verifying its address after a reboot or renewing its
+// lease. Requests for a reserved address are acknowledged and requests for
+// any other address are refused. Clients without a reservation are not
+// ours to answer (RFC 2131 section 4.3.2).
+func (s *Server) answerRequest(req *pkt.Pkt) error {
+	xid := req.Header.XID
+	id, hwAddr := ClientIDFromPkt(req), ClientHwAddr(req)
+	if _, ok := s.Transaction(id, xid); ok {
+		// Assign answers it, or already did
+		return nil
+	}
+	ip, ok := s.Reservation(id)
+	if !ok {
+		slog.Debug("ignoring request from unknown client", "id", id, "state", req.RequestState())
+		return nil
+	}
+
+	verdict, reason := s.checkRequest(req, id, hwAddr, ip)
+	switch verdict {
+	case requestAccept:
+		s.begin(req, id, ip)
+		s.transition(id, xid, TxRequested, req.RequestState().String())
+		err := s.Ack(hwAddr, ip, xid)
+		if err != nil {
+			return fmt.Errorf("failed to send ack: %w", err)
+		}
+		s.transition(id, xid, TxAcked, "")
+	case requestRefuse:
+		s.begin(req, id, ip)
+		err := s.Nak(req, reason)
+		if err != nil {
+			return fmt.Errorf("failed to send nak: %w", err)
+		}
+		s.transition(id, xid, TxNaked, reason)
+	default:
+		slog.Debug("ignoring request", "id", id, "reason", reason)
+	}
+	return nil
+}
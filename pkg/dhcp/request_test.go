@@ -0,0 +1,157 @@
+package dhcp
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jon-ski/dhcpset/pkg/dhcp/pkt"
+)
+
+// clientRequest returns a DHCPREQUEST from hwAddr. serverID is set in
+// SELECTING state, ciaddr when renewing, neither for INIT-REBOOT.
+func clientRequest(hwAddr net.HardwareAddr, serverID, requested, ciaddr net.IP) *pkt.Pkt {
+	p := pkt.NewDiscover(hwAddr, 0x42)
+	p.Options.Set(pkt.NewOptionMessageType(pkt.MessageTypeRequest))
+	if serverID != nil {
+		p.Options.SetIP(pkt.OptionServerIdentifier, serverID)
+	}
+	if requested != nil {
+		p.Options.SetIP(pkt.OptionRequestedIPAddress, requested)
+	}
+	if ciaddr != nil {
+		p.Header.CIAddr = [4]byte(ciaddr.To4())
+	}
+	return p
+}
+
+func TestCheckRequest(t *testing.T) {
+	s := newTestServer(t)
+	ours, other := net.IPv4(192, 168, 0, 1), net.IPv4(192, 168, 0, 2)
+	ip, wrong := net.IPv4(192, 168, 0, 10), net.IPv4(192, 168, 0, 99)
+	tests := []struct {
+		name string
+		req  *pkt.Pkt
+		want requestVerdict
+	}{
+		{"selecting", clientRequest(hwAddr1, ours, ip, nil), requestAccept},
+		{"selecting wrong address", clientRequest(hwAddr1, ours, wrong, nil), requestRefuse},
+		{"selecting other server", clientRequest(hwAddr1, other, ip, nil), requestElsewhere},
+		{"init-reboot", clientRequest(hwAddr1, nil, ip, nil), requestAccept},
+		{"init-reboot wrong address", clientRequest(hwAddr1, nil, wrong, nil), requestRefuse},
+		{"init-reboot without address", clientRequest(hwAddr1, nil, nil, nil), requestRefuse},
+		{"renewing", clientRequest(hwAddr1, nil, nil, ip), requestAccept},
+		{"renewing wrong address", clientRequest(hwAddr1, nil, nil, wrong), requestRefuse},
+		{"other client", clientRequest(hwAddr2, ours, ip, nil), requestIgnore},
+	}
+	id := ClientIDFromPkt(pkt.NewDiscover(hwAddr1, 0x42))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reason := s.checkRequest(tt.req, id, hwAddr1, ip)
+			if got != tt.want {
+				t.Fatalf("got verdict %d (%s), want %d", got, reason, tt.want)
+			}
+			if got != requestAccept && reason == "" {
+				t.Fatal("no reason given")
+			}
+		})
+	}
+}
+
+// sentPacket is a packet passed to testCapture
+type sentPacket struct {
+	dst *net.UDPAddr
+	p   *pkt.Pkt
+}
+
+// testCapture decodes the packets a server writes
+type testCapture struct {
+	t    *testing.T
+	sent []sentPacket
+}
+
+func (c *testCapture) WriteUDP(ts time.Time, src, dst *net.UDPAddr, payload []byte) error {
+	p, err := pkt.NewFromBytes(payload)
+	if err != nil {
+		c.t.Errorf("server wrote an undecodable packet: %v", err)
+		return nil
+	}
+	c.sent = append(c.sent, sentPacket{dst: dst, p: p})
+	return nil
+}
+
+func TestAnswerRequestUnknownClient(t *testing.T) {
+	s := newTestServer(t)
+	c := &testCapture{t: t}
+	s.SetCapture(c)
+	req := clientRequest(hwAddr1, nil, net.IPv4(192, 168, 0, 10), nil)
+	if err := s.answerRequest(req); err != nil {
+		t.Fatalf("failed to answer request: %v", err)
+	}
+	if len(c.sent) != 0 {
+		t.Fatalf("answered a client without a reservation: %v", c.sent[0].p)
+	}
+	if _, ok := s.Transaction(ClientIDFromPkt(req), req.Header.XID); ok {
+		t.Fatal("started a transaction for a client without a reservation")
+	}
+}
+
+func TestRelayedNak(t *testing.T) {
+	s := newTestServer(t)
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Skipf("no loopback socket: %v", err)
+	}
+	defer conn.Close()
+	s.conn = conn
+	c := &testCapture{t: t}
+	s.SetCapture(c)
+
+	req := clientRequest(hwAddr1, nil, net.IPv4(192, 168, 0, 99), nil)
+	req.Header.GIAddr = [4]byte{127, 0, 0, 1}
+	relay, err := pkt.NewOptionRelayAgentInfo(pkt.RelayAgentInfo{
+		CircuitID: []byte("port 7"),
+		RemoteID:  []byte("switch-1"),
+	})
+	if err != nil {
+		t.Fatalf("failed to create option 82: %v", err)
+	}
+	req.Options.Add(relay)
+	id := ClientIDFromPkt(req)
+	s.Reserve(id, net.IPv4(192, 168, 0, 10))
+
+	if err := s.answerRequest(req); err != nil {
+		t.Fatalf("failed to answer request: %v", err)
+	}
+	if len(c.sent) != 1 {
+		t.Fatalf("sent %d packets, want 1", len(c.sent))
+	}
+	nak := c.sent[0]
+	if !nak.p.Is(pkt.MessageTypeNak) {
+		t.Fatalf("sent %v, want a DHCPNAK", nak.p.MessageType())
+	}
+	want := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 67}
+	if nak.dst.String() != want.String() {
+		t.Errorf("sent to %v, want the relay at %v", nak.dst, want)
+	}
+	got, ok := nak.p.Options.Get(pkt.OptionRelayAgentInfo)
+	if !ok || !bytes.Equal(got.Data, relay.Data) {
+		t.Errorf("option 82 not echoed: %v", got)
+	}
+	opts := nak.p.Options.Options
+	if last := opts[len(opts)-1]; last.Type != pkt.OptionEnd {
+		t.Errorf("last option is %v, want End", last.Type)
+	}
+	if msg, ok := nak.p.Options.GetString(pkt.OptionMessage); !ok || msg == "" {
+		t.Error("NAK has no message")
+	}
+
+	tx, ok := s.Transaction(id, req.Header.XID)
+	if !ok || tx.State != TxNaked {
+		t.Fatalf("got transaction %+v, want state %v", tx, TxNaked)
+	}
+	if ip, _ := s.Reservation(id); !ip.Equal(net.IPv4(192, 168, 0, 10)) {
+		t.Errorf("reservation changed to %v", ip)
+	}
+}
@@ -0,0 +1,371 @@
+package dhcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"time"
+
+	"github.com/jon-ski/dhcpset/pkg/dhcp/pkt"
+)
+
+// ErrTimeout is returned when a client does not answer in time
+var ErrTimeout = errors.New("timed out waiting for client")
+
+// TxState is the state of a transaction with a client
+type TxState int
+
+const (
+	TxInit      TxState = iota // tracked, nothing sent yet
+	TxOffered                  // OFFER sent, waiting for a REQUEST
+	TxRequested                // REQUEST received
+	TxAcked                    // ACK sent, the address is assigned
+	TxNaked                    // NAK sent, the client must start over
+	TxTimedOut                 // the client stopped answering
+	TxAborted                  // the server gave up, its context was canceled
+	TxLost                     // the client took another server's offer
+	TxDeclined                 // the client found the address in use
+	TxReleased                 // the client gave the address back
+)
+
+var txStateNames = map[TxState]string{
+	TxInit:      "init",
+	TxOffered:   "offered",
+	TxRequested: "requested",
+	TxAcked:     "acked",
+	TxNaked:     "nak'd",
+	TxTimedOut:  "timed out",
+	TxAborted:   "aborted",
+	TxLost:      "lost",
+	TxDeclined:  "declined",
+	TxReleased:  "released",
+}
+
+func (s TxState) String() string {
+	if name, ok := txStateNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("TxState(%d)", int(s))
+}
+
+// Done reports whether the transaction is over
+func (s TxState) Done() bool {
+	switch s {
+	case TxAcked, TxNaked, TxTimedOut, TxAborted, TxLost, TxDeclined, TxReleased:
+		return true
+	}
+	return false
+}
+
+// Transaction is one exchange with a client, identified by its XID and
+// the client. Clients pick XIDs at random, so two may use the same one.
+type Transaction struct {
+	XID      uint32
+	ClientID ClientID
+	HwAddr   net.HardwareAddr
+	IP       net.IP
+	State    TxState
+	// Offers is the number of OFFERs sent
+	Offers  int
+	Started time.Time
+	Updated time.Time
+}
+
+// Event reports a transaction entering a state. A retransmitted OFFER is
+// reported as the offered state again with a higher Offers count.
+type Event struct {
+	Time time.Time
+	Transaction
+	// Prev is the previous state, TxInit for a new transaction
+	Prev TxState
+	// Reason explains timeouts, aborts, NAKs, lost clients and declines
+	Reason string
+}
+
+func (e Event) String() string {
+	var s string
+	switch e.State {
+	case TxOffered:
+		s = fmt.Sprintf("OFFER %d sent for %v", e.Offers, e.IP)
+	case TxRequested:
+		s = fmt.Sprintf("REQUEST received for %v", e.IP)
+	case TxAcked:
+		s = fmt.Sprintf("%v assigned", e.IP)
+	case TxDeclined:
+		s = fmt.Sprintf("%v declined", e.IP)
+	case TxReleased:
+		s = fmt.Sprintf("%v released", e.IP)
+	default:
+		s = e.State.String()
+	}
+	if e.Reason != "" {
+		s += ": " + e.Reason
+	}
+	return s
+}
+
+// Backoff controls OFFER retransmission. The wait for a REQUEST starts at
+// Initial and doubles after each OFFER up to Max, like the client
+// retransmissions of RFC 2131 section 4.1. The transaction times out once
+// Retries OFFERs have been resent without an answer.
+type Backoff struct {
+	Initial time.Duration
+	Max     time.Duration
+	Retries int
+}
+
+// DefaultBackoff waits 2+4+8+16+16 seconds in total
+var DefaultBackoff = Backoff{
+	Initial: 2 * time.Second,
+	Max:     16 * time.Second,
+	Retries: 4,
+}
+
+// TxExpiry is how long a transaction is kept after its last change, and
+// a packet from a client without one after it arrived
+const TxExpiry = 5 * time.Minute
+
+// expireInterval keeps a burst of packets from sweeping on every packet
+const expireInterval = time.Second
+
+// eventBuffer is the number of events kept for a slow reader
+const eventBuffer = 64
+
+// txKey identifies a transaction
+type txKey struct {
+	xid uint32
+	id  ClientID
+}
+
+// SetBackoff sets the OFFER retransmission policy. It defaults to
+// DefaultBackoff.
+func (s *Server) SetBackoff(b Backoff) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.backoff = b
+}
+
+// Events returns the channel transaction events are sent on. Events are
+// dropped when nobody reads them.
+func (s *Server) Events() <-chan Event {
+	return s.events
+}
+
+// Transaction returns the current state of the transaction xid with the
+// client id
+func (s *Server) Transaction(id ClientID, xid uint32) (Transaction, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tx, ok := s.txs[txKey{xid, id}]
+	if !ok {
+		return Transaction{}, false
+	}
+	return *tx, true
+}
+
+// begin starts tracking a transaction with the client id, replacing any
+// earlier one of the client with the same XID, and expires stale ones
+func (s *Server) begin(req *pkt.Pkt, id ClientID, ip net.IP) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire(now)
+	s.txs[txKey{req.Header.XID, id}] = &Transaction{
+		XID:      req.Header.XID,
+		ClientID: id,
+		HwAddr:   ClientHwAddr(req),
+		IP:       ip,
+		Started:  now,
+		Updated:  now,
+	}
+}
+
+// transition moves the transaction xid with the client id to state and
+// reports it
+func (s *Server) transition(id ClientID, xid uint32, state TxState, reason string) {
+	now := time.Now()
+	s.mu.Lock()
+	tx, ok := s.txs[txKey{xid, id}]
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	prev := tx.State
+	tx.State = state
+	tx.Updated = now
+	if state == TxOffered {
+		tx.Offers++
+	}
+	ev := Event{
+		Time:        now,
+		Transaction: *tx,
+		Prev:        prev,
+		Reason:      reason,
+	}
+	s.mu.Unlock()
+
+	slog.Debug("transaction", "xid", fmt.Sprintf("0x%08x", xid), "id", id,
+		"from", prev, "to", state, "reason", reason)
+	select {
+	case s.events <- ev:
+	default:
+		slog.Warn("dropping transaction event", "event", ev.String())
+	}
+}
+
+// expire forgets transactions that have not changed for TxExpiry, along
+// with their last request, and requests older than TxExpiry that no
+// transaction uses. Unfinished transactions are reported as timed out. It
+// sweeps at most once per expireInterval. s.mu must be held.
+func (s *Server) expire(now time.Time) {
+	if now.Sub(s.swept) < expireInterval {
+		return
+	}
+	s.swept = now
+	for xid, rs := range s.requests {
+		rs = slices.DeleteFunc(rs, func(r request) bool {
+			_, ok := s.txs[txKey{xid, r.id}]
+			return !ok && now.Sub(r.seen) >= TxExpiry
+		})
+		if len(rs) == 0 {
+			delete(s.requests, xid)
+		} else {
+			s.requests[xid] = rs
+		}
+	}
+	for key, tx := range s.txs {
+		if now.Sub(tx.Updated) < TxExpiry {
+			continue
+		}
+		delete(s.txs, key)
+		s.forget(key.id, key.xid)
+		if tx.State.Done() {
+			continue
+		}
+		prev := tx.State
+		tx.State = TxTimedOut
+		tx.Updated = now
+		select {
+		case s.events <- Event{Time: now, Transaction: *tx, Prev: prev, Reason: "expired"}:
+		default:
+		}
+	}
+}
+
+// Assign gives ip to the client identified by id, in transaction xid. It
+// sends an OFFER, resending it with backoff until the client sends a
+// REQUEST, then answers with an ACK and reserves the address under id, so
+// clients with a client identifier (option 61) are not told apart by
+// chaddr. BOOTP clients get a single BOOTREPLY instead.
+//
+// It returns ErrTimeout if the client never requests the offer or the
+// deadline of ctx passes, and the context error if ctx is canceled. A
+// REQUEST for another address is answered with a DHCPNAK and ErrNak, one
+// for another server's offer ends with ErrOtherServer. Addresses a client
+// declined are refused with ErrConflict.
+func (s *Server) Assign(
+	ctx context.Context, id ClientID, hwAddr net.HardwareAddr, ip net.IP, xid uint32,
+) error {
+	if s.Conflicted(ip) {
+		return fmt.Errorf("%w: %v", ErrConflict, ip)
+	}
+	req := s.requestFor(hwAddr, xid)
+	s.begin(req, id, ip)
+	if req.IsBOOTP() {
+		err := s.BootReply(hwAddr, ip, xid)
+		if err != nil {
+			return fmt.Errorf("failed to send bootreply: %w", err)
+		}
+		s.transition(id, xid, TxAcked, "BOOTREPLY sent")
+		s.Reserve(id, ip)
+		return nil
+	}
+
+	// subscribe before the first OFFER so a quick REQUEST is not missed
+	sub := s.Subscribe(requestFilter(xid))
+	defer sub.Close()
+
+	s.mu.Lock()
+	backoff := s.backoff
+	s.mu.Unlock()
+	wait := backoff.Initial
+	for offers := 1; ; offers++ {
+		err := s.Offer(hwAddr, ip, xid)
+		if err != nil {
+			return fmt.Errorf("failed to send offer: %w", err)
+		}
+		s.transition(id, xid, TxOffered, "")
+
+		attempt, cancel := context.WithTimeout(ctx, wait)
+		_, err = s.awaitRequest(attempt, sub, id, hwAddr, ip)
+		cancel()
+		if err == nil {
+			break
+		}
+		switch {
+		case ctx.Err() != nil:
+			s.abort(id, xid, ctx.Err())
+			return ctxErr(ctx.Err())
+		case !errors.Is(err, ErrTimeout):
+			return err
+		case offers > backoff.Retries:
+			s.transition(id, xid, TxTimedOut, fmt.Sprintf("no REQUEST after %d offers", offers))
+			return ErrTimeout
+		}
+		wait = min(2*wait, backoff.Max)
+	}
+	s.transition(id, xid, TxRequested, "")
+
+	err := s.Ack(hwAddr, ip, xid)
+	if err != nil {
+		return fmt.Errorf("failed to send ack: %w", err)
+	}
+	s.transition(id, xid, TxAcked, "")
+	s.Reserve(id, ip)
+	return nil
+}
+
+// awaitRequest waits for the REQUEST of the client identified by id and
+// hwAddr on sub, ignoring REQUESTs from other clients. A REQUEST for
+// anything but ip is refused with a DHCPNAK.
+func (s *Server) awaitRequest(
+	ctx context.Context, sub *Subscription, id ClientID, hwAddr net.HardwareAddr, ip net.IP,
+) (*pkt.Pkt, error) {
+	for {
+		req, err := sub.Next(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read packet: %w", err)
+		}
+		xid := req.Header.XID
+		verdict, reason := s.checkRequest(req, id, hwAddr, ip)
+		switch verdict {
+		case requestAccept:
+			slog.Debug("received request", "packet", req)
+			return req, nil
+		case requestIgnore:
+			slog.Warn("ignoring request", "xid", fmt.Sprintf("0x%08x", xid), "reason", reason)
+		case requestElsewhere:
+			s.transition(id, xid, TxLost, reason)
+			return nil, fmt.Errorf("%w: %s", ErrOtherServer, reason)
+		case requestRefuse:
+			err := s.Nak(req, reason)
+			if err != nil {
+				return nil, fmt.Errorf("failed to send nak: %w", err)
+			}
+			s.transition(id, xid, TxNaked, reason)
+			return nil, fmt.Errorf("%w: %s", ErrNak, reason)
+		}
+	}
+}
+
+// abort ends a transaction whose context is done
+func (s *Server) abort(id ClientID, xid uint32, err error) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		s.transition(id, xid, TxTimedOut, "deadline exceeded")
+		return
+	}
+	s.transition(id, xid, TxAborted, err.Error())
+}
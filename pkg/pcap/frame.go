@@ -0,0 +1,123 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"net"
+)
+
+const (
+	etherTypeIPv4 = 0x0800
+	etherTypeVLAN = 0x8100
+	protoUDP      = 17
+
+	ethernetHeaderLen = 14
+	ipv4HeaderLen     = 20
+	udpHeaderLen      = 8
+)
+
+// syntheticMAC derives a locally administered MAC address from an IPv4
+// address, or returns the broadcast address for broadcast destinations
+func syntheticMAC(ip net.IP) net.HardwareAddr {
+	ip4 := ip.To4()
+	if ip4 == nil || ip4.Equal(net.IPv4bcast) {
+		return net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	}
+	return net.HardwareAddr{0x02, 0x00, ip4[0], ip4[1], ip4[2], ip4[3]}
+}
+
+// EncodeUDP wraps payload in synthetic Ethernet, IPv4 and UDP headers
+func EncodeUDP(src, dst *net.UDPAddr, payload []byte) []byte {
+	frame := make([]byte, ethernetHeaderLen+ipv4HeaderLen+udpHeaderLen+len(payload))
+
+	// Ethernet
+	eth := frame[:ethernetHeaderLen]
+	copy(eth[0:6], syntheticMAC(dst.IP))
+	copy(eth[6:12], syntheticMAC(src.IP))
+	binary.BigEndian.PutUint16(eth[12:], etherTypeIPv4)
+
+	// IPv4
+	ip := frame[ethernetHeaderLen : ethernetHeaderLen+ipv4HeaderLen]
+	ip[0] = 0x45 // version 4, 5 word header
+	binary.BigEndian.PutUint16(ip[2:], uint16(ipv4HeaderLen+udpHeaderLen+len(payload)))
+	ip[8] = 64 // TTL
+	ip[9] = protoUDP
+	copy(ip[12:16], ipv4OrZero(src.IP))
+	copy(ip[16:20], ipv4OrZero(dst.IP))
+	binary.BigEndian.PutUint16(ip[10:], checksum(ip))
+
+	// UDP, checksum left at zero which IPv4 allows
+	udp := frame[ethernetHeaderLen+ipv4HeaderLen:]
+	binary.BigEndian.PutUint16(udp[0:], uint16(src.Port))
+	binary.BigEndian.PutUint16(udp[2:], uint16(dst.Port))
+	binary.BigEndian.PutUint16(udp[4:], uint16(udpHeaderLen+len(payload)))
+	copy(udp[udpHeaderLen:], payload)
+	return frame
+}
+
+func ipv4OrZero(ip net.IP) net.IP {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+	return net.IPv4zero.To4()
+}
+
+// checksum computes the internet checksum of an IPv4 header
+func checksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(b[i:]))
+	}
+	for sum > 0xffff {
+		sum = sum>>16 + sum&0xffff
+	}
+	return ^uint16(sum)
+}
+
+// UDP returns the addresses and payload of a captured IPv4 UDP packet
+func (p Packet) UDP() (src, dst *net.UDPAddr, payload []byte, ok bool) {
+	b := p.Data
+	switch p.LinkType {
+	case LinkTypeEthernet:
+		if len(b) < ethernetHeaderLen {
+			return nil, nil, nil, false
+		}
+		etherType := binary.BigEndian.Uint16(b[12:])
+		b = b[ethernetHeaderLen:]
+		for etherType == etherTypeVLAN {
+			if len(b) < 4 {
+				return nil, nil, nil, false
+			}
+			etherType = binary.BigEndian.Uint16(b[2:])
+			b = b[4:]
+		}
+		if etherType != etherTypeIPv4 {
+			return nil, nil, nil, false
+		}
+	case LinkTypeRaw, LinkTypeIPv4:
+	default:
+		return nil, nil, nil, false
+	}
+
+	if len(b) < ipv4HeaderLen || b[0]>>4 != 4 || b[9] != protoUDP {
+		return nil, nil, nil, false
+	}
+	ihl := int(b[0]&0x0f) * 4
+	total := int(binary.BigEndian.Uint16(b[2:]))
+	if ihl < ipv4HeaderLen || total < ihl+udpHeaderLen || total > len(b) {
+		return nil, nil, nil, false
+	}
+	// fragments other than the first cannot be decoded on their own
+	if binary.BigEndian.Uint16(b[6:])&0x1fff != 0 {
+		return nil, nil, nil, false
+	}
+	srcIP := net.IP(append([]byte(nil), b[12:16]...))
+	dstIP := net.IP(append([]byte(nil), b[16:20]...))
+	udp := b[ihl:total]
+	length := int(binary.BigEndian.Uint16(udp[4:]))
+	if length < udpHeaderLen || length > len(udp) {
+		return nil, nil, nil, false
+	}
+	src = &net.UDPAddr{IP: srcIP, Port: int(binary.BigEndian.Uint16(udp[0:]))}
+	dst = &net.UDPAddr{IP: dstIP, Port: int(binary.BigEndian.Uint16(udp[2:]))}
+	return src, dst, udp[udpHeaderLen:length], true
+}
@@ -0,0 +1,44 @@
+package pcap
+
+import (
+	"errors"
+	"time"
+)
+
+// LinkType is the link layer header type of captured packets
+type LinkType uint32
+
+const (
+	LinkTypeEthernet LinkType = 1
+	LinkTypeRaw      LinkType = 101
+	LinkTypeIPv4     LinkType = 228
+)
+
+// File format magic numbers
+const (
+	magicMicros = 0xa1b2c3d4
+	magicNanos  = 0xa1b23c4d
+
+	blockTypeSHB = 0x0a0d0d0a
+	blockTypeIDB = 0x00000001
+	blockTypeSPB = 0x00000003
+	blockTypeEPB = 0x00000006
+
+	byteOrderMagic = 0x1a2b3c4d
+)
+
+// snapLen is the largest packet we write or accept
+const snapLen = 65535
+
+var (
+	ErrBadMagic  = errors.New("not a pcap or pcapng file")
+	ErrBadBlock  = errors.New("malformed pcapng block")
+	ErrBadRecord = errors.New("malformed pcap record")
+)
+
+// Packet is a single captured frame
+type Packet struct {
+	Timestamp time.Time
+	LinkType  LinkType
+	Data      []byte
+}
@@ -0,0 +1,162 @@
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jon-ski/dhcpset/pkg/dhcp/pkt"
+)
+
+var (
+	clientAddr = &net.UDPAddr{IP: net.IPv4zero, Port: portClient}
+	serverAddr = &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: portServer}
+	broadcast  = &net.UDPAddr{IP: net.IPv4bcast, Port: portServer}
+)
+
+// discover returns an encoded DHCPDISCOVER with transaction ID xid
+func discover(t *testing.T, xid uint32) []byte {
+	t.Helper()
+	hwAddr := net.HardwareAddr{0x00, 0x0b, 0x82, 0x01, 0xfc, 0x42}
+	b, err := pkt.NewDiscover(hwAddr, xid).MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to marshal discover: %v", err)
+	}
+	return b
+}
+
+func TestWriteReadDHCP(t *testing.T) {
+	tests := []struct {
+		name      string
+		newWriter func(io.Writer) (*Writer, error)
+	}{
+		{"pcap", NewWriter},
+		{"pcapng", NewNgWriter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := tt.newWriter(&buf)
+			if err != nil {
+				t.Fatalf("failed to create writer: %v", err)
+			}
+			ts := time.Date(2024, time.May, 1, 12, 0, 0, 123456000, time.UTC)
+			writes := []struct {
+				src, dst *net.UDPAddr
+				xid      uint32
+			}{
+				{clientAddr, broadcast, 1},
+				{serverAddr, &net.UDPAddr{IP: net.IPv4bcast, Port: portClient}, 2},
+			}
+			for i, wr := range writes {
+				err := w.WriteUDP(ts.Add(time.Duration(i)*time.Second), wr.src, wr.dst, discover(t, wr.xid))
+				if err != nil {
+					t.Fatalf("failed to write packet: %v", err)
+				}
+			}
+			// not DHCP, skipped by the reader
+			err = w.WriteUDP(ts, serverAddr, &net.UDPAddr{IP: net.IPv4bcast, Port: 53}, []byte("dns"))
+			if err != nil {
+				t.Fatalf("failed to write packet: %v", err)
+			}
+
+			pkts, err := ReadDHCP(&buf)
+			if err != nil {
+				t.Fatalf("failed to read capture: %v", err)
+			}
+			if len(pkts) != len(writes) {
+				t.Fatalf("got %d packets, want %d", len(pkts), len(writes))
+			}
+			for i, wr := range writes {
+				got := pkts[i]
+				if want := ts.Add(time.Duration(i) * time.Second); !got.Timestamp.Equal(want) {
+					t.Errorf("packet %d: got time %v, want %v", i, got.Timestamp, want)
+				}
+				if got.Src.String() != wr.src.String() || got.Dst.String() != wr.dst.String() {
+					t.Errorf("packet %d: got %v -> %v, want %v -> %v", i, got.Src, got.Dst, wr.src, wr.dst)
+				}
+				if got.Pkt.Header.XID != wr.xid {
+					t.Errorf("packet %d: got xid %d, want %d", i, got.Pkt.Header.XID, wr.xid)
+				}
+			}
+		})
+	}
+}
+
+// ngBlock encodes a pcapng block in the given byte order
+func ngBlock(order binary.AppendByteOrder, blockType uint32, body []byte) []byte {
+	body = append(body, make([]byte, (4-len(body)%4)%4)...)
+	total := uint32(12 + len(body))
+	b := order.AppendUint32(nil, blockType)
+	b = order.AppendUint32(b, total)
+	b = append(b, body...)
+	return order.AppendUint32(b, total)
+}
+
+// ngSection encodes a section with one Ethernet interface using if_tsresol
+// tsresol and one enhanced packet block holding frame at ticks
+func ngSection(order binary.AppendByteOrder, tsresol byte, ticks uint64, frame []byte) []byte {
+	shb := order.AppendUint32(nil, byteOrderMagic)
+	shb = order.AppendUint16(shb, 1)
+	shb = order.AppendUint16(shb, 0)
+	shb = order.AppendUint64(shb, ^uint64(0))
+
+	idb := order.AppendUint16(nil, uint16(LinkTypeEthernet))
+	idb = order.AppendUint16(idb, 0)
+	idb = order.AppendUint32(idb, snapLen)
+	idb = order.AppendUint16(idb, 9) // if_tsresol
+	idb = order.AppendUint16(idb, 1)
+	idb = append(idb, tsresol, 0, 0, 0)
+	idb = order.AppendUint32(idb, 0) // opt_endofopt
+
+	epb := order.AppendUint32(nil, 0)
+	epb = order.AppendUint32(epb, uint32(ticks>>32))
+	epb = order.AppendUint32(epb, uint32(ticks))
+	epb = order.AppendUint32(epb, uint32(len(frame)))
+	epb = order.AppendUint32(epb, uint32(len(frame)))
+	epb = append(epb, frame...)
+
+	var b []byte
+	b = append(b, ngBlock(order, blockTypeSHB, shb)...)
+	b = append(b, ngBlock(order, blockTypeIDB, idb)...)
+	return append(b, ngBlock(order, blockTypeEPB, epb)...)
+}
+
+func TestReadNgSections(t *testing.T) {
+	ts := time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)
+	var file []byte
+	// nanosecond ticks in a little endian section
+	file = append(file, ngSection(binary.LittleEndian, 9,
+		uint64(ts.Add(123456789*time.Nanosecond).UnixNano()),
+		EncodeUDP(clientAddr, broadcast, discover(t, 1)))...)
+	// 1/1024 second ticks in a big endian section
+	file = append(file, ngSection(binary.BigEndian, 0x80|10,
+		uint64(ts.Unix())<<10|512,
+		EncodeUDP(clientAddr, broadcast, discover(t, 2)))...)
+
+	pkts, err := ReadDHCP(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("failed to read capture: %v", err)
+	}
+	want := []struct {
+		ts  time.Time
+		xid uint32
+	}{
+		{ts.Add(123456789 * time.Nanosecond), 1},
+		{ts.Add(500 * time.Millisecond), 2},
+	}
+	if len(pkts) != len(want) {
+		t.Fatalf("got %d packets, want %d", len(pkts), len(want))
+	}
+	for i, w := range want {
+		if !pkts[i].Timestamp.Equal(w.ts) {
+			t.Errorf("packet %d: got time %v, want %v", i, pkts[i].Timestamp, w.ts)
+		}
+		if pkts[i].Pkt.Header.XID != w.xid {
+			t.Errorf("packet %d: got xid %d, want %d", i, pkts[i].Pkt.Header.XID, w.xid)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"time"
+
+	"github.com/jon-ski/dhcpset/pkg/dhcp/pkt"
+)
+
+// DHCP ports
+const (
+	portServer = 67
+	portClient = 68
+)
+
+// iface is a pcapng interface description
+type iface struct {
+	linkType LinkType
+	// tsRate is the number of timestamp ticks per second
+	tsRate uint64
+}
+
+// Reader reads packets from a pcap or pcapng file
+type Reader struct {
+	r     io.Reader
+	order binary.ByteOrder
+	ng    bool
+
+	// pcap
+	linkType LinkType
+	nanos    bool
+
+	// pcapng
+	ifaces []iface
+}
+
+// NewReader detects the file format from the header of r
+func NewReader(r io.Reader) (*Reader, error) {
+	var magic [4]byte
+	_, err := io.ReadFull(r, magic[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file header: %w", err)
+	}
+	rd := &Reader{r: r}
+
+	if binary.LittleEndian.Uint32(magic[:]) == blockTypeSHB {
+		rd.ng = true
+		err = rd.readSHB()
+		if err != nil {
+			return nil, err
+		}
+		return rd, nil
+	}
+
+	switch {
+	case binary.LittleEndian.Uint32(magic[:]) == magicMicros:
+		rd.order = binary.LittleEndian
+	case binary.BigEndian.Uint32(magic[:]) == magicMicros:
+		rd.order = binary.BigEndian
+	case binary.LittleEndian.Uint32(magic[:]) == magicNanos:
+		rd.order = binary.LittleEndian
+		rd.nanos = true
+	case binary.BigEndian.Uint32(magic[:]) == magicNanos:
+		rd.order = binary.BigEndian
+		rd.nanos = true
+	default:
+		return nil, ErrBadMagic
+	}
+	hdr := make([]byte, 20)
+	_, err = io.ReadFull(r, hdr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file header: %w", err)
+	}
+	rd.linkType = LinkType(rd.order.Uint32(hdr[16:]) & 0x0fffffff)
+	return rd, nil
+}
+
+// readSHB reads the rest of a section header block, after its type
+func (rd *Reader) readSHB() error {
+	var hdr [8]byte
+	_, err := io.ReadFull(rd.r, hdr[:])
+	if err != nil {
+		return fmt.Errorf("failed to read section header: %w", err)
+	}
+	switch {
+	case binary.LittleEndian.Uint32(hdr[4:]) == byteOrderMagic:
+		rd.order = binary.LittleEndian
+	case binary.BigEndian.Uint32(hdr[4:]) == byteOrderMagic:
+		rd.order = binary.BigEndian
+	default:
+		return ErrBadMagic
+	}
+	total := int(rd.order.Uint32(hdr[0:]))
+	if total < 28 || total%4 != 0 {
+		return ErrBadBlock
+	}
+	// skip the remainder of the block, a new section has new interfaces
+	_, err = io.CopyN(io.Discard, rd.r, int64(total-12))
+	if err != nil {
+		return fmt.Errorf("failed to read section header: %w", err)
+	}
+	rd.ifaces = nil
+	return nil
+}
+
+// Next returns the next packet, or io.EOF at the end of the file
+func (rd *Reader) Next() (Packet, error) {
+	if rd.ng {
+		return rd.nextBlock()
+	}
+	hdr := make([]byte, 16)
+	_, err := io.ReadFull(rd.r, hdr)
+	if err == io.EOF {
+		return Packet{}, io.EOF
+	}
+	if err != nil {
+		return Packet{}, fmt.Errorf("failed to read record header: %w", err)
+	}
+	sec := rd.order.Uint32(hdr[0:])
+	frac := rd.order.Uint32(hdr[4:])
+	capLen := rd.order.Uint32(hdr[8:])
+	if capLen > snapLen*4 {
+		return Packet{}, ErrBadRecord
+	}
+	data := make([]byte, capLen)
+	_, err = io.ReadFull(rd.r, data)
+	if err != nil {
+		return Packet{}, fmt.Errorf("failed to read record: %w", err)
+	}
+	nsec := int64(frac) * 1000
+	if rd.nanos {
+		nsec = int64(frac)
+	}
+	return Packet{
+		Timestamp: time.Unix(int64(sec), nsec),
+		LinkType:  rd.linkType,
+		Data:      data,
+	}, nil
+}
+
+// nextBlock reads pcapng blocks until a packet block is found
+func (rd *Reader) nextBlock() (Packet, error) {
+	for {
+		var hdr [8]byte
+		_, err := io.ReadFull(rd.r, hdr[:])
+		if err == io.EOF {
+			return Packet{}, io.EOF
+		}
+		if err != nil {
+			return Packet{}, fmt.Errorf("failed to read block header: %w", err)
+		}
+		blockType := rd.order.Uint32(hdr[0:])
+		if blockType == blockTypeSHB {
+			// the byte order may change with each section
+			rd.r = io.MultiReader(bytes.NewReader(append([]byte(nil), hdr[4:]...)), rd.r)
+			err = rd.readSHB()
+			if err != nil {
+				return Packet{}, err
+			}
+			continue
+		}
+
+		total := int(rd.order.Uint32(hdr[4:]))
+		if total < 12 || total%4 != 0 || total > snapLen*4 {
+			return Packet{}, ErrBadBlock
+		}
+		body := make([]byte, total-8)
+		_, err = io.ReadFull(rd.r, body)
+		if err != nil {
+			return Packet{}, fmt.Errorf("failed to read block: %w", err)
+		}
+		body = body[:len(body)-4]
+
+		switch blockType {
+		case blockTypeIDB:
+			if len(body) < 8 {
+				return Packet{}, ErrBadBlock
+			}
+			rd.ifaces = append(rd.ifaces, iface{
+				linkType: LinkType(rd.order.Uint16(body[0:])),
+				tsRate:   rd.tsResolution(body[8:]),
+			})
+
+		case blockTypeEPB:
+			if len(body) < 20 {
+				return Packet{}, ErrBadBlock
+			}
+			id := int(rd.order.Uint32(body[0:]))
+			capLen := int(rd.order.Uint32(body[12:]))
+			if id >= len(rd.ifaces) || 20+capLen > len(body) {
+				return Packet{}, ErrBadBlock
+			}
+			ts := uint64(rd.order.Uint32(body[4:]))<<32 | uint64(rd.order.Uint32(body[8:]))
+			return Packet{
+				Timestamp: timestamp(ts, rd.ifaces[id].tsRate),
+				LinkType:  rd.ifaces[id].linkType,
+				Data:      body[20 : 20+capLen],
+			}, nil
+
+		case blockTypeSPB:
+			if len(body) < 4 || len(rd.ifaces) == 0 {
+				return Packet{}, ErrBadBlock
+			}
+			origLen := int(rd.order.Uint32(body[0:]))
+			data := body[4:]
+			if origLen < len(data) {
+				data = data[:origLen]
+			}
+			return Packet{
+				LinkType: rd.ifaces[0].linkType,
+				Data:     data,
+			}, nil
+		}
+		// other block types are skipped
+	}
+}
+
+// tsResolution reads the if_tsresol option of an interface description
+// and returns the number of timestamp ticks per second
+func (rd *Reader) tsResolution(opts []byte) uint64 {
+	const optEnd, optTsResol = 0, 9
+	rate := uint64(1e6)
+	for len(opts) >= 4 {
+		code := rd.order.Uint16(opts[0:])
+		n := int(rd.order.Uint16(opts[2:]))
+		if code == optEnd || 4+n > len(opts) {
+			break
+		}
+		if code == optTsResol && n >= 1 {
+			v := opts[4]
+			// binary resolutions are not whole nanoseconds, keep the rate
+			if v&0x80 == 0 && v <= 9 {
+				rate = uint64(math.Pow10(int(v)))
+			} else if v&0x80 != 0 && v&0x7f <= 29 {
+				rate = uint64(1) << (v & 0x7f)
+			}
+		}
+		opts = opts[4+(n+3)/4*4:]
+	}
+	return rate
+}
+
+func timestamp(ticks, rate uint64) time.Time {
+	nsec := ticks % rate * uint64(time.Second) / rate
+	return time.Unix(int64(ticks/rate), int64(nsec))
+}
+
+// DHCPPacket is a DHCP message extracted from a capture
+type DHCPPacket struct {
+	Timestamp time.Time
+	Src       *net.UDPAddr
+	Dst       *net.UDPAddr
+	Pkt       *pkt.Pkt
+}
+
+// ReadDHCP reads every UDP packet to or from the DHCP ports in r and
+// decodes its payload. Packets that fail to decode are skipped.
+func ReadDHCP(r io.Reader) ([]DHCPPacket, error) {
+	rd, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	var pkts []DHCPPacket
+	for {
+		p, err := rd.Next()
+		if errors.Is(err, io.EOF) {
+			return pkts, nil
+		}
+		if err != nil {
+			return pkts, err
+		}
+		src, dst, payload, ok := p.UDP()
+		if !ok || !isDHCPPort(src.Port) || !isDHCPPort(dst.Port) {
+			continue
+		}
+		dp, err := pkt.NewFromBytes(payload)
+		if err != nil {
+			continue
+		}
+		pkts = append(pkts, DHCPPacket{
+			Timestamp: p.Timestamp,
+			Src:       src,
+			Dst:       dst,
+			Pkt:       dp,
+		})
+	}
+}
+
+func isDHCPPort(port int) bool {
+	return port == portServer || port == portClient
+}
@@ -0,0 +1,104 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Writer writes captured packets as a pcap or pcapng file with
+// Ethernet framing. It is safe for concurrent use.
+type Writer struct {
+	mu sync.Mutex
+	w  io.Writer
+	ng bool
+}
+
+// NewWriter writes a pcap file header to w and returns a Writer
+func NewWriter(w io.Writer) (*Writer, error) {
+	hdr := make([]byte, 24)
+	binary.LittleEndian.PutUint32(hdr[0:], magicMicros)
+	binary.LittleEndian.PutUint16(hdr[4:], 2) // version 2.4
+	binary.LittleEndian.PutUint16(hdr[6:], 4)
+	binary.LittleEndian.PutUint32(hdr[16:], snapLen)
+	binary.LittleEndian.PutUint32(hdr[20:], uint32(LinkTypeEthernet))
+	_, err := w.Write(hdr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write pcap header: %w", err)
+	}
+	return &Writer{w: w}, nil
+}
+
+// NewNgWriter writes a pcapng section header and interface description
+// to w and returns a Writer
+func NewNgWriter(w io.Writer) (*Writer, error) {
+	shb := make([]byte, 16)
+	binary.LittleEndian.PutUint32(shb[0:], byteOrderMagic)
+	binary.LittleEndian.PutUint16(shb[4:], 1) // version 1.0
+	binary.LittleEndian.PutUint64(shb[8:], ^uint64(0))
+	err := writeBlock(w, blockTypeSHB, shb)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write section header: %w", err)
+	}
+
+	idb := make([]byte, 8)
+	binary.LittleEndian.PutUint16(idb[0:], uint16(LinkTypeEthernet))
+	binary.LittleEndian.PutUint32(idb[4:], snapLen)
+	err = writeBlock(w, blockTypeIDB, idb)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write interface description: %w", err)
+	}
+	return &Writer{w: w, ng: true}, nil
+}
+
+// writeBlock writes a pcapng block, padding the body to 32 bits
+func writeBlock(w io.Writer, blockType uint32, body []byte) error {
+	pad := (4 - len(body)%4) % 4
+	total := 12 + len(body) + pad
+	buf := make([]byte, total)
+	binary.LittleEndian.PutUint32(buf[0:], blockType)
+	binary.LittleEndian.PutUint32(buf[4:], uint32(total))
+	copy(buf[8:], body)
+	binary.LittleEndian.PutUint32(buf[total-4:], uint32(total))
+	_, err := w.Write(buf)
+	return err
+}
+
+// WritePacket writes a single Ethernet frame
+func (w *Writer) WritePacket(ts time.Time, frame []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(frame) > snapLen {
+		frame = frame[:snapLen]
+	}
+
+	if w.ng {
+		micros := uint64(ts.UnixMicro())
+		epb := make([]byte, 20+len(frame))
+		binary.LittleEndian.PutUint32(epb[0:], 0) // interface 0
+		binary.LittleEndian.PutUint32(epb[4:], uint32(micros>>32))
+		binary.LittleEndian.PutUint32(epb[8:], uint32(micros))
+		binary.LittleEndian.PutUint32(epb[12:], uint32(len(frame)))
+		binary.LittleEndian.PutUint32(epb[16:], uint32(len(frame)))
+		copy(epb[20:], frame)
+		return writeBlock(w.w, blockTypeEPB, epb)
+	}
+
+	rec := make([]byte, 16+len(frame))
+	binary.LittleEndian.PutUint32(rec[0:], uint32(ts.Unix()))
+	binary.LittleEndian.PutUint32(rec[4:], uint32(ts.Nanosecond()/1000))
+	binary.LittleEndian.PutUint32(rec[8:], uint32(len(frame)))
+	binary.LittleEndian.PutUint32(rec[12:], uint32(len(frame)))
+	copy(rec[16:], frame)
+	_, err := w.w.Write(rec)
+	return err
+}
+
+// WriteUDP writes a UDP payload with synthetic Ethernet, IPv4 and UDP
+// headers so the capture opens in Wireshark
+func (w *Writer) WriteUDP(ts time.Time, src, dst *net.UDPAddr, payload []byte) error {
+	return w.WritePacket(ts, EncodeUDP(src, dst, payload))
+}
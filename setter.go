@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"net"
+	"slices"
 	"strings"
 	"time"
 
@@ -11,15 +12,20 @@ import (
 	"github.com/charmbracelet/lipgloss/list"
 	"github.com/jon-ski/dhcpset/internal/styles"
 	"github.com/jon-ski/dhcpset/internal/tui/ipinput"
+	"github.com/jon-ski/dhcpset/pkg/dhcp"
 )
 
 type IPSetter struct {
 	state   int
+	id      dhcp.ClientID
 	hwaddr  net.HardwareAddr
 	txid    uint32
 	ipinput ipinput.Model
 	result  SetIPResult
 	pendLog *list.List
+
+	// conflicts are the addresses devices declined as in use
+	conflicts []net.IP
 }
 
 func NewIPSetter() IPSetter {
@@ -95,13 +101,30 @@ func (m IPSetter) Update(msg tea.Msg) (IPSetter, tea.Cmd) {
 
 func (m IPSetter) viewInfo() string {
 	var s strings.Builder
-	s.WriteString("Mac Address: ")
+	s.WriteString("Hardware Address: ")
+	hwaddr := m.hwaddr.String()
+	if hwaddr == "" {
+		hwaddr = "none, client ID " + m.id.String()
+	}
 	s.WriteString(
 		lipgloss.NewStyle().
 			Foreground(styles.Secondary()).
-			Render(m.hwaddr.String()),
+			Render(hwaddr),
 	)
 	s.WriteString("\n")
+	if len(m.conflicts) > 0 {
+		ips := make([]string, len(m.conflicts))
+		for i, ip := range m.conflicts {
+			ips[i] = ip.String()
+		}
+		s.WriteString("Addresses in use: ")
+		s.WriteString(
+			lipgloss.NewStyle().
+				Foreground(styles.Danger()).
+				Render(strings.Join(ips, ", ")),
+		)
+		s.WriteString("\n")
+	}
 	return s.String()
 }
 
@@ -168,6 +191,10 @@ func (m IPSetter) View() string {
 	return ""
 }
 
+func (m *IPSetter) SetClientID(id dhcp.ClientID) {
+	m.id = id
+}
+
 func (m *IPSetter) SetHwAddr(hwaddr net.HardwareAddr) {
 	m.hwaddr = hwaddr
 }
@@ -176,9 +203,21 @@ func (m *IPSetter) SetTXID(txid uint32) {
 	m.txid = txid
 }
 
+// AddConflict records an address a device declined. If it is the address
+// being set, the log says the device will not use it.
+func (m *IPSetter) AddConflict(ip net.IP) {
+	if !slices.ContainsFunc(m.conflicts, ip.Equal) {
+		m.conflicts = append(m.conflicts, ip)
+	}
+	if m.state != 0 && ip.Equal(m.ipinput.Value()) {
+		m.Log(fmt.Sprintf("Error: %v is in use by another device, the device declined it", ip))
+	}
+}
+
 func (m *IPSetter) SetIP() tea.Msg {
 	return SetIPRequest{
 		IP:  m.ipinput.Value(),
+		ID:  m.id,
 		MAC: m.hwaddr,
 		XID: m.txid,
 	}
@@ -186,6 +225,7 @@ func (m *IPSetter) SetIP() tea.Msg {
 
 type SetIPRequest struct {
 	IP  net.IP
+	ID  dhcp.ClientID
 	MAC net.HardwareAddr
 	XID uint32
 }